@@ -0,0 +1,160 @@
+package kyro
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Collect reads ch until it is closed and returns all received values in order.
+// A channel that is closed without any values yields an empty, non-nil slice.
+func Collect[T any](ch <-chan T) []T {
+	result := make([]T, 0)
+	for value := range ch {
+		result = append(result, value)
+	}
+	return result
+}
+
+// CollectN reads at most n values from ch and returns them in order. It returns early
+// if ch is closed before n values were received. The remaining values are left in ch.
+func CollectN[T any](ch <-chan T, n int) []T {
+	result := make([]T, 0, max(n, 0))
+	for len(result) < n {
+		value, ok := <-ch
+		if !ok {
+			break
+		}
+		result = append(result, value)
+	}
+	return result
+}
+
+// TapChannel returns a channel forwarding every value received from in, in order, after
+// calling fn with it. This allows logging or metering a stream without consuming it. The
+// returned channel is closed once in is closed. As the values are forwarded one at a time,
+// a slow fn or consumer slows down the whole stream.
+func TapChannel[T any](in <-chan T, fn func(T)) <-chan T {
+	out := make(chan T)
+
+	go func() {
+		defer close(out)
+		for value := range in {
+			fn(value)
+			out <- value
+		}
+	}()
+
+	return out
+}
+
+// StreamMap creates a PipelineStep that transforms a stream, i.e. a <-chan I (or chan I),
+// into a <-chan O by applying fn to every value. The values are mapped concurrently by up
+// to workers goroutines, so the order of the output stream is not specified unless workers
+// is 1. If workers is zero or less, runtime.GOMAXPROCS(0) goroutines are used.
+// Values for which fn returns an error are not passed on, but reported to onError together
+// with the error. onError may be called concurrently and may only be nil if such values are
+// meant to be dropped. The output stream is closed once the input stream is closed and
+// drained, or when the run is cancelled. A consumer that stops reading before the output
+// stream is closed has to cancel the run, e.g. one started with ExecuteWithContext, as the
+// goroutines otherwise block on sending their next value forever.
+func StreamMap[I any, O any](workers int, fn func(I) (O, error), onError ErrorNotifier[I]) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		if err := exec.wait(); err != nil {
+			return nil, err
+		}
+
+		in := asStream[I](input)
+		out := make(chan O)
+
+		if in == nil {
+			close(out)
+			return (<-chan O)(out), lastErr
+		}
+
+		if workers <= 0 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(workers)
+
+		for range workers {
+			go func() {
+				defer wg.Done()
+				for value := range in {
+					mapped, fnErr := fn(value)
+					if fnErr != nil {
+						if onError != nil {
+							onError(fnErr, value)
+						}
+						continue
+					}
+
+					select {
+					case out <- mapped:
+					case <-exec.done():
+						return
+					}
+				}
+			}()
+		}
+
+		go func() {
+			wg.Wait()
+			close(out)
+		}()
+
+		return (<-chan O)(out), lastErr
+	}).pipelineStep()
+}
+
+// StreamDedup creates a PipelineStep that passes on a stream, i.e. a <-chan T (or chan T),
+// emitting only the first occurrence of every value, in the order they are received. The
+// values seen so far are kept in a SimpleSet, so memory grows with the number of distinct
+// values for as long as the stream lasts; for an unbounded stream of ever new values, map it
+// to a key space of bounded size first. The output stream is closed once the input stream is
+// closed and drained, or when the run is cancelled.
+func StreamDedup[T comparable]() PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		if err := exec.wait(); err != nil {
+			return nil, err
+		}
+
+		in := asStream[T](input)
+		out := make(chan T)
+
+		if in == nil {
+			close(out)
+			return (<-chan T)(out), lastErr
+		}
+
+		go func() {
+			defer close(out)
+
+			seen := NewSimpleSet[T](0)
+			for value := range in {
+				if seen.Contains(value) {
+					continue
+				}
+				seen.Add(value)
+
+				select {
+				case out <- value:
+				case <-exec.done():
+					return
+				}
+			}
+		}()
+
+		return (<-chan T)(out), lastErr
+	}).pipelineStep()
+}
+
+// asStream asserts that input is a stream of T, accepting both receive-only and
+// bidirectional channels. A nil input yields a nil channel.
+func asStream[T any](input any) <-chan T {
+	if ch, ok := input.(chan T); ok {
+		return ch
+	}
+	return AssertIn[<-chan T](input)
+}
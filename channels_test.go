@@ -0,0 +1,222 @@
+package kyro_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/loggdme/kyro"
+)
+
+func sendAndClose(values ...int) <-chan int {
+	ch := make(chan int, len(values))
+	for _, value := range values {
+		ch <- value
+	}
+	close(ch)
+	return ch
+}
+
+func TestCollect(t *testing.T) {
+	if got := kyro.Collect(sendAndClose(1, 2, 3)); !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got %v", got)
+	}
+
+	if got := kyro.Collect(sendAndClose()); got == nil || len(got) != 0 {
+		t.Errorf("expected empty non-nil slice, got %#v", got)
+	}
+}
+
+func TestCollectN(t *testing.T) {
+	ch := sendAndClose(1, 2, 3, 4, 5)
+
+	if got := kyro.CollectN(ch, 2); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v", got)
+	}
+	if got := kyro.CollectN(ch, 10); !slices.Equal(got, []int{3, 4, 5}) {
+		t.Errorf("expected [3 4 5] from closed channel, got %v", got)
+	}
+	if got := kyro.CollectN(ch, 0); len(got) != 0 {
+		t.Errorf("expected no values for n=0, got %v", got)
+	}
+}
+
+func TestTapChannel(t *testing.T) {
+	var observed []int
+
+	values := kyro.Collect(kyro.TapChannel(sendAndClose(1, 2, 3, 4), func(value int) {
+		observed = append(observed, value)
+	}))
+
+	if !slices.Equal(values, []int{1, 2, 3, 4}) {
+		t.Errorf("expected all values to pass through, got %v", values)
+	}
+	if !slices.Equal(observed, []int{1, 2, 3, 4}) {
+		t.Errorf("expected fn to observe every value, got %v", observed)
+	}
+}
+
+func TestStreamMap_IntsToStrings(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := range 20 {
+			in <- i
+		}
+	}()
+
+	var failed []int
+	var failedMu sync.Mutex
+	toString := kyro.StreamMap(0, func(i int) (string, error) {
+		if i == 13 {
+			return "", errors.New("unlucky")
+		}
+		return strconv.Itoa(i), nil
+	}, func(err error, i int) {
+		failedMu.Lock()
+		defer failedMu.Unlock()
+		failed = append(failed, i)
+	})
+
+	output, err := toString(in, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := kyro.Collect(kyro.AssertIn[<-chan string](output))
+	slices.Sort(got)
+
+	var expected []string
+	for i := range 20 {
+		if i != 13 {
+			expected = append(expected, strconv.Itoa(i))
+		}
+	}
+	slices.Sort(expected)
+
+	if !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if !slices.Equal(failed, []int{13}) {
+		t.Errorf("expected the failure for 13 to be reported, got %v", failed)
+	}
+}
+
+func TestStreamMap_StopsOnCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The input stream never ends during the test, so only
+	// the cancellation can stop the workers.
+	stop := make(chan struct{})
+	defer close(stop)
+
+	in := make(chan int)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case in <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	output, err := kyro.ExecuteWithContext(ctx, kyro.InSequence(
+		func(input any, err error) (any, error) { return (<-chan int)(in), nil },
+		kyro.StreamMap(0, func(i int) (int, error) { return i * 2, nil }, nil),
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stream := kyro.AssertIn[<-chan int](output)
+	kyro.CollectN(stream, 3)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range stream {
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the output stream to be closed after the cancellation")
+	}
+}
+
+func TestStreamMap_LimitsWorkers(t *testing.T) {
+	in := make(chan int)
+	go func() {
+		defer close(in)
+		for i := range 10 {
+			in <- i
+		}
+	}()
+
+	var running, maxRunning int
+	var mu sync.Mutex
+	double := kyro.StreamMap(2, func(i int) (int, error) {
+		mu.Lock()
+		running++
+		maxRunning = max(maxRunning, running)
+		mu.Unlock()
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return i * 2, nil
+	}, nil)
+
+	output, err := double(in, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := kyro.Collect(kyro.AssertIn[<-chan int](output)); len(got) != 10 {
+		t.Errorf("expected 10 values, got %v", got)
+	}
+	if maxRunning != 2 {
+		t.Errorf("expected 2 values mapped at once, got %d", maxRunning)
+	}
+}
+
+func TestStreamMap_NilInput(t *testing.T) {
+	output, err := kyro.StreamMap(0, func(i int) (int, error) { return i, nil }, nil)(nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := kyro.Collect(kyro.AssertIn[<-chan int](output)); len(got) != 0 {
+		t.Errorf("expected empty stream, got %v", got)
+	}
+}
+
+func TestStreamDedup_FirstOccurrences(t *testing.T) {
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, value := range []string{"a", "b", "a", "c", "b", "b", "d", "a"} {
+			in <- value
+		}
+	}()
+
+	output, err := kyro.StreamDedup[string]()(in, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := kyro.Collect(kyro.AssertIn[<-chan string](output))
+	if expected := []string{"a", "b", "c", "d"}; !slices.Equal(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,135 @@
+package kyro
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// checkpointInterval is how often the checkpoint file is rewritten while processing.
+const checkpointInterval = time.Second
+
+// checkpoint tracks the highest line number up to which all lines have completed and
+// records it to a file. Lines complete out of order, so the completed lines past the
+// first gap are held until the gap is closed.
+type checkpoint struct {
+	path string
+
+	mutex sync.Mutex
+	// line is the highest line number up to which all lines have completed.
+	line int
+	// completed holds the completed line numbers past line.
+	completed map[int]struct{}
+	// written is the line number last written to the file.
+	written int
+}
+
+// newCheckpoint creates a checkpoint recording to the file at path, starting after line.
+func newCheckpoint(path string, line int) *checkpoint {
+	return &checkpoint{path: path, line: line, written: line, completed: make(map[int]struct{})}
+}
+
+// readCheckpoint returns the line number recorded in the checkpoint file at path, or 0 if
+// there is no such file yet.
+func readCheckpoint(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	line, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || line < 0 {
+		return 0, fmt.Errorf("invalid checkpoint %q in %s", data, path)
+	}
+	return line, nil
+}
+
+// complete marks the line with the given number as completed. It is a no-op on a nil checkpoint.
+func (c *checkpoint) complete(number int) {
+	if c == nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if number != c.line+1 {
+		c.completed[number] = struct{}{}
+		return
+	}
+
+	c.line = number
+	for {
+		if _, ok := c.completed[c.line+1]; !ok {
+			break
+		}
+		delete(c.completed, c.line+1)
+		c.line++
+	}
+}
+
+// write records the current line number to the file if it changed since the last write.
+// The file is replaced atomically, so a crash never leaves a partial checkpoint behind.
+func (c *checkpoint) write() error {
+	c.mutex.Lock()
+	line := c.line
+	c.mutex.Unlock()
+
+	if line == c.written {
+		return nil
+	}
+
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.Itoa(line)+"\n"), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return err
+	}
+
+	c.written = line
+	return nil
+}
+
+// start writes the checkpoint once per interval from a background goroutine until the
+// returned function is called, which stops the goroutine, writes the checkpoint a final
+// time and returns the error of that write. A failed write in between is retried on the
+// next tick, as the error may be transient. Without a checkpoint nothing is started.
+func (c *checkpoint) start(interval time.Duration) (stop func() error) {
+	if c == nil {
+		return func() error { return nil }
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				_ = c.write()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() error {
+		close(done)
+		<-stopped
+
+		return c.write()
+	}
+}
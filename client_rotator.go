@@ -5,6 +5,10 @@ import (
 	"sync"
 )
 
+// ErrRemoveAll is returned by RoundRobin.Remove if the predicate matches all elements,
+// as a RoundRobin cannot be empty.
+var ErrRemoveAll = errors.New("cannot remove all elements of a RoundRobin")
+
 // RoundRobin is a thread-safe wrapper for accessing slice elements
 // in a round-robin fashion.
 type RoundRobin[T any] struct {
@@ -37,3 +41,47 @@ func (rr *RoundRobin[T]) Next() T {
 
 	return item
 }
+
+// Remove removes every element for which predicate returns true and reports whether any
+// element was removed. As a RoundRobin cannot be empty, nothing is removed if predicate
+// matches all elements, and ErrRemoveAll is returned instead. The rotation continues with
+// the element that would have been next, or the one after it if that element was removed.
+// This method is safe for concurrent use by multiple goroutines.
+func (rr *RoundRobin[T]) Remove(predicate func(T) bool) (bool, error) {
+	rr.mu.Lock()
+	defer rr.mu.Unlock()
+
+	kept := make([]T, 0, len(rr.items))
+	nextIndex := 0
+
+	for i, item := range rr.items {
+		if predicate(item) {
+			continue
+		}
+
+		if i < rr.index {
+			nextIndex++
+		}
+		kept = append(kept, item)
+	}
+
+	if len(kept) == 0 {
+		return false, ErrRemoveAll
+	}
+	if len(kept) == len(rr.items) {
+		return false, nil
+	}
+
+	rr.items = kept
+	rr.index = nextIndex % len(kept)
+
+	return true, nil
+}
+
+// RemoveValue removes every element equal to item from rr, see RoundRobin.Remove. It is a
+// function rather than a method, as RoundRobin does not require its elements to be comparable.
+func RemoveValue[T comparable](rr *RoundRobin[T], item T) (bool, error) {
+	return rr.Remove(func(candidate T) bool {
+		return candidate == item
+	})
+}
@@ -0,0 +1,61 @@
+package kyro_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func nextN[T any](rr *kyro.RoundRobin[T], n int) []T {
+	result := make([]T, n)
+	for i := range result {
+		result[i] = rr.Next()
+	}
+	return result
+}
+
+func TestRoundRobin_RemoveValue(t *testing.T) {
+	rr, err := kyro.NewRoundRobin([]string{"a", "b", "c"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first := rr.Next(); first != "a" {
+		t.Fatalf("expected 'a', got %v", first)
+	}
+
+	if removed, err := kyro.RemoveValue(rr, "b"); !removed || err != nil {
+		t.Errorf("expected 'b' to be removed, got %v, %v", removed, err)
+	}
+	if removed, err := kyro.RemoveValue(rr, "unknown"); removed || err != nil {
+		t.Errorf("expected unknown value not to be removed without error, got %v, %v", removed, err)
+	}
+
+	got := nextN(rr, 4)
+	expected := []string{"c", "a", "c", "a"}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected rotation %v, got %v", expected, got)
+		}
+	}
+}
+
+func TestRoundRobin_Remove_KeepsAtLeastOneElement(t *testing.T) {
+	rr, _ := kyro.NewRoundRobin([]int{1, 2, 3, 4})
+
+	if removed, err := rr.Remove(func(i int) bool { return i%2 == 0 }); !removed || err != nil {
+		t.Errorf("expected even elements to be removed, got %v, %v", removed, err)
+	}
+	if removed, err := rr.Remove(func(i int) bool { return true }); removed || !errors.Is(err, kyro.ErrRemoveAll) {
+		t.Errorf("expected removal of all elements to be refused with ErrRemoveAll, got %v, %v", removed, err)
+	}
+
+	got := nextN(rr, 3)
+	expected := []int{1, 3, 1}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Fatalf("expected rotation %v, got %v", expected, got)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package kyro
 
-import "sync"
+import (
+	"encoding/json"
+	"sync"
+)
 
 type SimpleSet[T comparable] struct {
 	elements map[T]struct{}
@@ -16,6 +19,13 @@ func NewSimpleSet[T comparable](capacity int) *SimpleSet[T] {
 	}
 }
 
+// NewSimpleSetFromSlice creates a new SimpleSet holding the distinct elements of values.
+func NewSimpleSetFromSlice[T comparable](values []T) *SimpleSet[T] {
+	set := NewSimpleSet[T](len(values))
+	set.AddAll(values)
+	return set
+}
+
 // Add inserts an element into the set. If the element already exists, it will not be added again.
 func (s *SimpleSet[T]) Add(value T) {
 	s.mu.Lock()
@@ -24,6 +34,16 @@ func (s *SimpleSet[T]) Add(value T) {
 	s.elements[value] = struct{}{}
 }
 
+// AddAll inserts all elements of values into the set, taking the lock only once.
+func (s *SimpleSet[T]) AddAll(values []T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, value := range values {
+		s.elements[value] = struct{}{}
+	}
+}
+
 // Contains checks if the set contains the specified element.
 func (s *SimpleSet[T]) Contains(value T) bool {
 	s.mu.RLock()
@@ -33,6 +53,53 @@ func (s *SimpleSet[T]) Contains(value T) bool {
 	return exists
 }
 
+// ContainsAll reports whether the set contains every one of values, which holds for no values.
+func (s *SimpleSet[T]) ContainsAll(values ...T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, value := range values {
+		if _, exists := s.elements[value]; !exists {
+			return false
+		}
+	}
+	return true
+}
+
+// ContainsAny reports whether the set contains at least one of values, which fails for no values.
+func (s *SimpleSet[T]) ContainsAny(values ...T) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, value := range values {
+		if _, exists := s.elements[value]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove deletes an element from the set. Removing an element that is not in the set is a no-op.
+func (s *SimpleSet[T]) Remove(value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.elements, value)
+}
+
+// Len returns the number of elements in the set.
+func (s *SimpleSet[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.elements)
+}
+
+// IsEmpty reports whether the set has no elements.
+func (s *SimpleSet[T]) IsEmpty() bool {
+	return s.Len() == 0
+}
+
 // Clear removes all elements from the set, effectively resetting it.
 func (s *SimpleSet[T]) Clear() {
 	s.mu.Lock()
@@ -43,6 +110,7 @@ func (s *SimpleSet[T]) Clear() {
 
 // AsSlice returns all elements in the set as a slice.
 // The order of elements in the slice is not guaranteed to be the same as the order of insertion.
+// The slice is never nil, an empty set yields an empty slice, so it encodes to [] in JSON.
 func (s *SimpleSet[T]) AsSlice() []T {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -54,3 +122,109 @@ func (s *SimpleSet[T]) AsSlice() []T {
 
 	return keys
 }
+
+// ForEach calls fn for every element of the set, in no particular order, until fn returns
+// false. Unlike AsSlice, it does not copy the elements. The read lock is held while fn runs,
+// so fn must not call back into the set: a method modifying the set deadlocks, and even
+// reading may deadlock if another goroutine is waiting to modify the set.
+func (s *SimpleSet[T]) ForEach(fn func(T) bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for elem := range s.elements {
+		if !fn(elem) {
+			return
+		}
+	}
+}
+
+// MarshalJSON encodes the set as a JSON array of its elements, in no particular order.
+func (s *SimpleSet[T]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.AsSlice())
+}
+
+// UnmarshalJSON decodes a JSON array into the set, replacing its elements. Duplicates in
+// the array are collapsed. On error, the set is left unchanged.
+func (s *SimpleSet[T]) UnmarshalJSON(data []byte) error {
+	var values []T
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+
+	elements := make(map[T]struct{}, len(values))
+	for _, value := range values {
+		elements[value] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.elements = elements
+	return nil
+}
+
+// Union returns a new set holding the elements that are in s, in other, or in both.
+// This method is safe for concurrent use by multiple goroutines.
+func (s *SimpleSet[T]) Union(other *SimpleSet[T]) *SimpleSet[T] {
+	// other is copied before s is locked, so that no two locks are ever held at once.
+	others := other.AsSlice()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := NewSimpleSet[T](len(s.elements) + len(others))
+	for elem := range s.elements {
+		result.elements[elem] = struct{}{}
+	}
+	for _, elem := range others {
+		result.elements[elem] = struct{}{}
+	}
+	return result
+}
+
+// Intersection returns a new set holding the elements that are in both s and other.
+// This method is safe for concurrent use by multiple goroutines.
+func (s *SimpleSet[T]) Intersection(other *SimpleSet[T]) *SimpleSet[T] {
+	// As in Union, other is copied before s is locked.
+	others := other.AsSlice()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := NewSimpleSet[T](0)
+	for _, elem := range others {
+		if _, exists := s.elements[elem]; exists {
+			result.elements[elem] = struct{}{}
+		}
+	}
+	return result
+}
+
+// Difference returns a new set holding the elements of s that are not in other.
+// This method is safe for concurrent use by multiple goroutines.
+func (s *SimpleSet[T]) Difference(other *SimpleSet[T]) *SimpleSet[T] {
+	// As in Union, other is copied before s is locked.
+	others := other.AsSlice()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := NewSimpleSet[T](0)
+	for elem := range s.elements {
+		result.elements[elem] = struct{}{}
+	}
+	for _, elem := range others {
+		delete(result.elements, elem)
+	}
+	return result
+}
+
+// NonNil returns s, or an empty slice if s is nil. Unlike a nil slice, the result encodes
+// to [] instead of null in JSON. All methods of SimpleSet returning a slice already
+// guarantee a non-nil result.
+func NonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
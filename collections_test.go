@@ -0,0 +1,265 @@
+package kyro_test
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestSimpleSet_AsSlice_NonNil(t *testing.T) {
+	set := kyro.NewSimpleSet[string](0)
+
+	if set.AsSlice() == nil {
+		t.Error("expected non-nil slice for a new set")
+	}
+
+	set.Add("a")
+	set.Clear()
+
+	if set.AsSlice() == nil {
+		t.Error("expected non-nil slice for a cleared set")
+	}
+
+	encoded, err := json.Marshal(set.AsSlice())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(encoded) != "[]" {
+		t.Errorf("expected [], got %s", encoded)
+	}
+}
+
+func TestSimpleSet_Len(t *testing.T) {
+	set := kyro.NewSimpleSet[string](0)
+
+	if set.Len() != 0 || !set.IsEmpty() {
+		t.Errorf("expected empty set, got length %d", set.Len())
+	}
+
+	set.Add("a")
+	set.Add("b")
+	set.Add("a")
+	if set.Len() != 2 || set.IsEmpty() {
+		t.Errorf("expected 2 elements, got %d", set.Len())
+	}
+
+	set.Remove("a")
+	set.Remove("missing")
+	if set.Len() != 1 || set.Contains("a") {
+		t.Errorf("expected only b to remain, got %v", set.AsSlice())
+	}
+
+	set.Clear()
+	if set.Len() != 0 || !set.IsEmpty() {
+		t.Errorf("expected empty set after Clear, got length %d", set.Len())
+	}
+}
+
+func TestNewSimpleSetFromSlice(t *testing.T) {
+	set := kyro.NewSimpleSetFromSlice([]string{"a", "b", "a", "c", "b", "a"})
+
+	if set.Len() != 3 {
+		t.Errorf("expected 3 distinct elements, got %d", set.Len())
+	}
+
+	set.AddAll([]string{"c", "d", "d"})
+	values := set.AsSlice()
+	slices.Sort(values)
+	if expected := []string{"a", "b", "c", "d"}; !slices.Equal(values, expected) {
+		t.Errorf("expected %v, got %v", expected, values)
+	}
+}
+
+func TestSimpleSet_ContainsAllAny(t *testing.T) {
+	set := kyro.NewSimpleSetFromSlice([]string{"a", "b", "c"})
+
+	tests := []struct {
+		name   string
+		values []string
+		all    bool
+		any    bool
+	}{
+		{name: "full membership", values: []string{"a", "c"}, all: true, any: true},
+		{name: "partial membership", values: []string{"a", "x"}, all: false, any: true},
+		{name: "no membership", values: []string{"x", "y"}, all: false, any: false},
+		{name: "no values", values: nil, all: true, any: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := set.ContainsAll(tt.values...); got != tt.all {
+				t.Errorf("expected ContainsAll %v, got %v", tt.all, got)
+			}
+			if got := set.ContainsAny(tt.values...); got != tt.any {
+				t.Errorf("expected ContainsAny %v, got %v", tt.any, got)
+			}
+		})
+	}
+}
+
+func TestSimpleSet_ForEach(t *testing.T) {
+	set := kyro.NewSimpleSetFromSlice([]int{1, 2, 3, 4, 5})
+
+	sum := 0
+	set.ForEach(func(value int) bool {
+		sum += value
+		return true
+	})
+	if sum != 15 {
+		t.Errorf("expected sum 15, got %d", sum)
+	}
+}
+
+func TestSimpleSet_ForEach_StopsEarly(t *testing.T) {
+	set := kyro.NewSimpleSetFromSlice([]int{1, 2, 3, 4, 5})
+
+	calls := 0
+	found := false
+	set.ForEach(func(value int) bool {
+		calls++
+		found = value == 3
+		return !found
+	})
+
+	if !found {
+		t.Error("expected to find 3")
+	}
+	if calls > set.Len() {
+		t.Errorf("expected at most %d calls, got %d", set.Len(), calls)
+	}
+
+	calls = 0
+	set.ForEach(func(value int) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("expected a single call when stopping right away, got %d", calls)
+	}
+}
+
+func TestSimpleSet_JSONRoundTrip(t *testing.T) {
+	set := kyro.NewSimpleSetFromSlice([]string{"a", "b", "c"})
+
+	encoded, err := json.Marshal(set)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var values []string
+	if err := json.Unmarshal(encoded, &values); err != nil {
+		t.Fatalf("expected a JSON array, got %s", encoded)
+	}
+	slices.Sort(values)
+	if expected := []string{"a", "b", "c"}; !slices.Equal(values, expected) {
+		t.Errorf("expected %v, got %s", expected, encoded)
+	}
+
+	decoded := kyro.NewSimpleSetFromSlice([]string{"stale"})
+	if err := json.Unmarshal(encoded, decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	values = decoded.AsSlice()
+	slices.Sort(values)
+	if expected := []string{"a", "b", "c"}; !slices.Equal(values, expected) {
+		t.Errorf("expected decoded set to be replaced with %v, got %v", expected, values)
+	}
+
+	var config struct {
+		Tags *kyro.SimpleSet[string] `json:"tags"`
+	}
+	if err := json.Unmarshal([]byte(`{"tags":["x","y","x"]}`), &config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if config.Tags.Len() != 2 || !config.Tags.Contains("y") {
+		t.Errorf("expected tags x and y, got %v", config.Tags.AsSlice())
+	}
+}
+
+func TestNonNil(t *testing.T) {
+	if result := kyro.NonNil[int](nil); result == nil || len(result) != 0 {
+		t.Errorf("expected empty non-nil slice, got %#v", result)
+	}
+
+	values := []int{1, 2}
+	if result := kyro.NonNil(values); &result[0] != &values[0] {
+		t.Error("expected non-nil slice to be returned as is")
+	}
+}
+
+func TestSimpleSet_Algebra(t *testing.T) {
+	setOf := func(values ...int) *kyro.SimpleSet[int] {
+		set := kyro.NewSimpleSet[int](len(values))
+		for _, value := range values {
+			set.Add(value)
+		}
+		return set
+	}
+	sorted := func(set *kyro.SimpleSet[int]) []int {
+		values := set.AsSlice()
+		slices.Sort(values)
+		return values
+	}
+
+	tests := []struct {
+		name         string
+		a, b         *kyro.SimpleSet[int]
+		union        []int
+		intersection []int
+		difference   []int
+	}{
+		{name: "disjoint", a: setOf(1, 2), b: setOf(3, 4), union: []int{1, 2, 3, 4}, intersection: []int{}, difference: []int{1, 2}},
+		{name: "overlapping", a: setOf(1, 2, 3), b: setOf(2, 3, 4), union: []int{1, 2, 3, 4}, intersection: []int{2, 3}, difference: []int{1}},
+		{name: "identical", a: setOf(1, 2), b: setOf(1, 2), union: []int{1, 2}, intersection: []int{1, 2}, difference: []int{}},
+		{name: "empty and non-empty", a: setOf(), b: setOf(1), union: []int{1}, intersection: []int{}, difference: []int{}},
+		{name: "non-empty and empty", a: setOf(1), b: setOf(), union: []int{1}, intersection: []int{}, difference: []int{1}},
+		{name: "both empty", a: setOf(), b: setOf(), union: []int{}, intersection: []int{}, difference: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sorted(tt.a.Union(tt.b)); !slices.Equal(got, tt.union) {
+				t.Errorf("expected union %v, got %v", tt.union, got)
+			}
+			if got := sorted(tt.a.Intersection(tt.b)); !slices.Equal(got, tt.intersection) {
+				t.Errorf("expected intersection %v, got %v", tt.intersection, got)
+			}
+			if got := sorted(tt.a.Difference(tt.b)); !slices.Equal(got, tt.difference) {
+				t.Errorf("expected difference %v, got %v", tt.difference, got)
+			}
+		})
+	}
+}
+
+func TestSimpleSet_Algebra_SameSet(t *testing.T) {
+	set := kyro.NewSimpleSet[int](0)
+	set.Add(1)
+
+	if got := set.Union(set).AsSlice(); !slices.Equal(got, []int{1}) {
+		t.Errorf("expected union [1], got %v", got)
+	}
+	if got := set.Difference(set).AsSlice(); len(got) != 0 {
+		t.Errorf("expected empty difference, got %v", got)
+	}
+}
+
+func TestSimpleSet_Algebra_Concurrent(t *testing.T) {
+	a := kyro.NewSimpleSet[int](0)
+	b := kyro.NewSimpleSet[int](0)
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(3)
+		go func() { defer wg.Done(); a.Add(i); b.Add(i * 2) }()
+		go func() { defer wg.Done(); a.Union(b) }()
+		go func() { defer wg.Done(); b.Intersection(a) }()
+	}
+	wg.Wait()
+
+	if got := a.Intersection(b).AsSlice(); len(got) != 50 {
+		t.Errorf("expected 50 common elements, got %d", len(got))
+	}
+}
@@ -0,0 +1,355 @@
+package kyro
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// execution holds the state of a single pipeline run started by one of the context
+// aware Execute variants. It is handed to every scopedStep of the run, so that the
+// combinators can observe it without changing the PipelineStep signature.
+type execution struct {
+	ctx     context.Context
+	trace   *tracer
+	hooks   *Hooks
+	limiter *RateLimiter
+	panics  *panicSite
+	exited  *atomic.Bool
+
+	// forwardPanics makes the combinators recover panics of the steps they run on goroutines
+	// of their own and re-raise them on the goroutine running the combinator, see recovering.
+	forwardPanics bool
+
+	// slots holds a token for every parallel step running under the concurrency limit
+	// of the execution, nil without a limit. hold is set on the copies of the execution
+	// handed to such a step and tells whether the step currently holds its token.
+	slots chan struct{}
+	hold  *atomic.Bool
+}
+
+// panicSite records the innermost traced step a panic passed through during a Validate run.
+type panicSite struct {
+	mu   sync.Mutex
+	step string
+	set  bool
+}
+
+// tracer collects the traces of the traced steps of an execution. It is shared by
+// pointer, so copies of an execution created with withContext record into the same trace.
+type tracer struct {
+	mu     sync.Mutex
+	traces []StepTrace
+}
+
+// scopedStep is a step that runs within an execution. The combinators are written as
+// scopedSteps, so that they receive the execution of the run explicitly and can hand it on
+// to the steps they invoke. A scopedStep that does not invoke further steps, i.e. a leaf
+// step, has to wait for the limiter of the execution itself, see wait.
+type scopedStep func(exec *execution, input any, lastErr error) (output any, err error)
+
+// scoped carries an execution through the PipelineStep signature. It is only ever handed
+// to the PipelineStep of a scopedStep, which unwraps it before running, so steps written
+// by the user never see it.
+type scoped struct {
+	exec  *execution
+	value any
+}
+
+// scopedStepCode is the code pointer shared by all PipelineSteps returned by pipelineStep.
+var scopedStepCode = reflect.ValueOf(PipelineStep(scopedStep(nil).invoke)).Pointer()
+
+// pipelineStep converts s into a PipelineStep. Called directly, it runs s without an execution.
+func (s scopedStep) pipelineStep() PipelineStep {
+	return s.invoke
+}
+
+// invoke runs s within the execution carried by a scoped input, or without one.
+func (s scopedStep) invoke(input any, lastErr error) (output any, err error) {
+	if in, ok := input.(scoped); ok {
+		return s(in.exec, in.value, lastErr)
+	}
+	return s(nil, input, lastErr)
+}
+
+// isScoped reports whether step was created by scopedStep.pipelineStep, i.e. whether it
+// accepts its input within a scoped envelope.
+func isScoped(step PipelineStep) bool {
+	return step != nil && reflect.ValueOf(step).Pointer() == scopedStepCode
+}
+
+// call invokes step with the given input within the execution. Only a scopedStep receives
+// the execution, any other step is called with the plain input. As such a step is a leaf
+// step, call first waits for the limiter of the execution, if any. Without an execution the
+// step is called directly, so pipelines started with Execute pay no extra cost.
+func (x *execution) call(step PipelineStep, input any, lastErr error) (any, error) {
+	if x == nil {
+		return step(input, lastErr)
+	}
+
+	if isScoped(step) {
+		return step(scoped{exec: x, value: input}, lastErr)
+	}
+
+	if err := x.wait(); err != nil {
+		return nil, err
+	}
+	return step(input, lastErr)
+}
+
+// callAt invokes step like call and fires the hooks of the execution around it.
+// The index is the position of step within the calling combinator.
+func (x *execution) callAt(index int, step PipelineStep, input any, lastErr error) (any, error) {
+	if x == nil || x.hooks == nil {
+		return x.call(step, input, lastErr)
+	}
+
+	if x.hooks.BeforeStep != nil {
+		x.hooks.BeforeStep(index)
+	}
+
+	start := time.Now()
+	output, err := x.call(step, input, lastErr)
+
+	if x.hooks.AfterStep != nil {
+		x.hooks.AfterStep(index, output, err, time.Since(start))
+	}
+
+	return output, err
+}
+
+// wait waits until the limiter of the execution allows a leaf step to run.
+// It returns nil right away if the execution is not rate limited.
+func (x *execution) wait() error {
+	if x == nil || x.limiter == nil {
+		return nil
+	}
+	return x.limiter.limiter.Wait(x.ctx)
+}
+
+// withContext returns a copy of the execution that uses ctx instead. Without an
+// execution it returns nil, as there is nothing to hand the context to.
+func (x *execution) withContext(ctx context.Context) *execution {
+	if x == nil {
+		return nil
+	}
+
+	child := *x
+	child.ctx = ctx
+	return &child
+}
+
+// record appends trace to the traces of the execution if it is traced.
+func (x *execution) record(trace StepTrace) {
+	if x == nil || x.trace == nil {
+		return
+	}
+
+	x.trace.mu.Lock()
+	defer x.trace.mu.Unlock()
+
+	x.trace.traces = append(x.trace.traces, trace)
+}
+
+// acquireSlot waits until a parallel step may start under the concurrency limit of the
+// execution and returns the execution to run the step with, which has to be released with
+// releaseSlot once the step returns. It returns false if stop is closed or the execution is
+// cancelled first. Without a limit it returns the execution itself right away.
+func (x *execution) acquireSlot(stop <-chan struct{}) (*execution, bool) {
+	if x == nil || x.slots == nil {
+		return x, true
+	}
+
+	select {
+	case x.slots <- struct{}{}:
+	case <-stop:
+		return nil, false
+	case <-x.ctx.Done():
+		return nil, false
+	}
+
+	child := *x
+	child.hold = &atomic.Bool{}
+	child.hold.Store(true)
+	return &child, true
+}
+
+// releaseSlot returns the slot acquired with acquireSlot, unless it was given up with yieldSlot.
+func (x *execution) releaseSlot() {
+	if x != nil && x.hold != nil && x.hold.CompareAndSwap(true, false) {
+		<-x.slots
+	}
+}
+
+// yieldSlot gives up the slot of the parallel step the execution runs in, if any, while the
+// step waits for parallel steps of its own, so that nested combinators cannot deadlock by
+// holding all slots while waiting. The returned function takes a slot again, unless the
+// execution is cancelled first.
+func (x *execution) yieldSlot() (reclaim func()) {
+	if x == nil || x.hold == nil || !x.hold.CompareAndSwap(true, false) {
+		return func() {}
+	}
+
+	<-x.slots
+	return func() {
+		select {
+		case x.slots <- struct{}{}:
+			x.hold.Store(true)
+		case <-x.ctx.Done():
+		}
+	}
+}
+
+// markExited records that a sequence of the execution was stopped by ExitOnErrorStep.
+func (x *execution) markExited() {
+	if x != nil && x.exited != nil {
+		x.exited.Store(true)
+	}
+}
+
+// locatesPanics reports whether the traced steps of the execution note panics passing
+// through them, which is only the case when run by Validate.
+func (x *execution) locatesPanics() bool {
+	return x != nil && x.panics != nil
+}
+
+// forwardsPanics reports whether panics of steps run on goroutines started by a combinator
+// are forwarded to the goroutine running the combinator.
+func (x *execution) forwardsPanics() bool {
+	return x != nil && x.forwardPanics
+}
+
+// forwardingPanics returns a copy of the execution that forwards panics. Without an
+// execution it returns a new one, so that even a pipeline started with Execute forwards them.
+func (x *execution) forwardingPanics() *execution {
+	if x == nil {
+		return &execution{ctx: context.Background(), forwardPanics: true}
+	}
+	if x.forwardPanics {
+		return x
+	}
+
+	child := *x
+	child.forwardPanics = true
+	return &child
+}
+
+// recovering runs call on a goroutine started by a combinator. If the execution forwards
+// panics, a panic raised by call is recovered and returned as a *stepPanic error, which the
+// combinator re-raises on its own goroutine with repanic. Otherwise the panic crashes the program.
+func (x *execution) recovering(call func() (any, error)) (output any, err error) {
+	if x.forwardsPanics() {
+		defer func() {
+			if r := recover(); r != nil {
+				output, err = nil, newStepPanic(r)
+			}
+		}()
+	}
+
+	return call()
+}
+
+// stepPanic carries a panic raised on a goroutine started by a combinator to the goroutine
+// running the combinator. It keeps the stack of the goroutine the panic was raised on.
+type stepPanic struct {
+	value any
+	stack []byte
+}
+
+// newStepPanic wraps the recovered value r, unless it is a forwarded panic already.
+func newStepPanic(r any) *stepPanic {
+	if p, ok := r.(*stepPanic); ok {
+		return p
+	}
+	return &stepPanic{value: r, stack: debug.Stack()}
+}
+
+func (p *stepPanic) Error() string {
+	return fmt.Sprintf("panic in step: %v\n%s", p.value, p.stack)
+}
+
+// repanic re-raises the panic carried by err if it was returned by recovering.
+func repanic(err error) {
+	if p, ok := err.(*stepPanic); ok {
+		panic(p)
+	}
+}
+
+// recovered returns the value and stack of the recovered value r. The stack is the one of
+// the goroutine a forwarded panic was raised on, or else the one of the calling goroutine.
+func recovered(r any) (value any, stack []byte) {
+	if p, ok := r.(*stepPanic); ok {
+		return p.value, p.stack
+	}
+	return r, debug.Stack()
+}
+
+// notePanic records that a panic passed through the traced step with the given name.
+// Only the first call is kept, which is made by the innermost step.
+func (x *execution) notePanic(name string) {
+	x.panics.mu.Lock()
+	defer x.panics.mu.Unlock()
+
+	if !x.panics.set {
+		x.panics.step, x.panics.set = name, true
+	}
+}
+
+// run invokes pipeline within the execution from a separate goroutine. If the context
+// of the execution is cancelled before the pipeline completes, run returns immediately
+// with the context error and the result of the pipeline is discarded.
+func (x *execution) run(pipeline PipelineStep, input any) (output any, err error) {
+	if err := x.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		output any
+		err    error
+	}
+
+	// done is buffered so the pipeline goroutine never blocks on sending its
+	// result, even if nobody is listening anymore after a cancellation.
+	done := make(chan result, 1)
+
+	go func() {
+		output, err := x.call(pipeline, input, nil)
+		done <- result{output: output, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.output, r.err
+	case <-x.ctx.Done():
+		return nil, x.ctx.Err()
+	}
+}
+
+// context returns the context of the execution or context.Background() without one.
+func (x *execution) context() context.Context {
+	if x == nil {
+		return context.Background()
+	}
+	return x.ctx
+}
+
+// done returns a channel that is closed when the execution is cancelled. Without an
+// execution it returns a nil channel, which blocks forever when used in a select.
+func (x *execution) done() <-chan struct{} {
+	if x == nil {
+		return nil
+	}
+	return x.ctx.Done()
+}
+
+// err returns the error of the context of the execution or nil without one.
+func (x *execution) err() error {
+	if x == nil {
+		return nil
+	}
+	return x.ctx.Err()
+}
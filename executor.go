@@ -0,0 +1,59 @@
+package kyro
+
+import "context"
+
+// Executor runs pipelines under a shared context so that all of them can be
+// cancelled with a single call, e.g. when the request that started them goes away.
+type Executor struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	limiter *RateLimiter
+	slots   chan struct{}
+}
+
+// NewExecutor creates a new Executor derived from the given parent context.
+// Cancelling the parent context cancels all pipelines run by the executor as well.
+func NewExecutor(parent context.Context) *Executor {
+	ctx, cancel := context.WithCancel(parent)
+	return &Executor{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the context shared by all pipelines run by the executor.
+func (e *Executor) Context() context.Context {
+	return e.ctx
+}
+
+// WithGlobalRateLimit makes all pipelines run by the executor share rl. Every invocation of
+// a leaf step, i.e. a step that is not a combinator like InSequence or InParallel, waits for
+// rl before it starts, which throttles the total work of the pipelines per second however
+// deeply the combinators are nested. A step retried by RetryStep waits for every attempt.
+func (e *Executor) WithGlobalRateLimit(rl *RateLimiter) *Executor {
+	e.limiter = rl
+	return e
+}
+
+// WithMaxConcurrency limits the number of steps run by InParallel and its variants that are
+// running at the same time to n, across all pipelines run by the executor and however deeply
+// the combinators are nested. Further steps wait until a running one returns. A step waiting
+// for parallel steps of its own does not count against the limit while it waits.
+func (e *Executor) WithMaxConcurrency(n int) *Executor {
+	e.slots = nil
+	if n > 0 {
+		e.slots = make(chan struct{}, n)
+	}
+	return e
+}
+
+// Execute runs the pipeline with the context of the executor, see ExecuteWithContext.
+// If the executor is cancelled before the pipeline completes, Execute returns immediately
+// with the context error and the result of the pipeline is discarded.
+func (e *Executor) Execute(pipeline PipelineStep) (output any, err error) {
+	return (&execution{ctx: e.ctx, limiter: e.limiter, slots: e.slots}).run(pipeline, nil)
+}
+
+// Cancel aborts all pipelines currently run by the executor and makes every
+// subsequent call to Execute fail with context.Canceled. It is safe to call
+// Cancel multiple times.
+func (e *Executor) Cancel() {
+	e.cancel()
+}
@@ -0,0 +1,218 @@
+package kyro_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestExecutor_Execute_Success(t *testing.T) {
+	executor := kyro.NewExecutor(context.Background())
+	defer executor.Cancel()
+
+	output, err := executor.Execute(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.AsPipelineStep(addOneStep),
+	))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 11 {
+		t.Errorf("expected output 11, got %v", output)
+	}
+}
+
+func TestExecutor_Execute_RawSteps(t *testing.T) {
+	executor := kyro.NewExecutor(context.Background()).
+		WithGlobalRateLimit(kyro.NewRateLimiter(1000, 10)).
+		WithMaxConcurrency(2)
+	defer executor.Cancel()
+
+	increment := func(input any, err error) (any, error) {
+		return input.(int) + 1, err
+	}
+
+	output, err := executor.Execute(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		increment,
+		kyro.InParallel(increment, increment, increment),
+	))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{12, 12, 12}) {
+		t.Errorf("expected output [12 12 12], got %v", output)
+	}
+}
+
+func TestExecutor_Cancel_AbortsAllPipelines(t *testing.T) {
+	executor := kyro.NewExecutor(context.Background())
+
+	release := make(chan struct{})
+	defer close(release)
+
+	blockingStep := func(input any, err error) (any, error) {
+		<-release
+		return "finished", nil
+	}
+
+	errs := make(chan error, 2)
+	for range 2 {
+		go func() {
+			_, err := executor.Execute(kyro.InSequence(
+				kyro.AsPipelineGenerator(intGenerator),
+				kyro.InParallel(blockingStep, blockingStep),
+			))
+			errs <- err
+		}()
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	executor.Cancel()
+
+	for range 2 {
+		select {
+		case err := <-errs:
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected context.Canceled, got: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("pipeline did not return after Cancel")
+		}
+	}
+}
+
+func TestExecutor_Execute_AfterCancel(t *testing.T) {
+	executor := kyro.NewExecutor(context.Background())
+	executor.Cancel()
+	executor.Cancel()
+
+	called := false
+	_, err := executor.Execute(func(input any, err error) (any, error) {
+		called = true
+		return nil, nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+	if called {
+		t.Error("expected pipeline not to run after Cancel")
+	}
+}
+
+func TestExecutor_WithGlobalRateLimit(t *testing.T) {
+	executor := kyro.NewExecutor(context.Background()).
+		WithGlobalRateLimit(kyro.NewRateLimiter(100, 1))
+	defer executor.Cancel()
+
+	var invocations atomic.Int32
+	countingStep := func(input any, err error) (any, error) {
+		invocations.Add(1)
+		return input, nil
+	}
+
+	parallel := make([]kyro.PipelineStep, 10)
+	for i := range parallel {
+		parallel[i] = countingStep
+	}
+
+	start := time.Now()
+	_, err := executor.Execute(kyro.InSequence(
+		countingStep,
+		kyro.InParallel(parallel...),
+		countingStep,
+	))
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := invocations.Load(); n != 12 {
+		t.Errorf("expected 12 step invocations, got %d", n)
+	}
+
+	// Only the 12 leaf steps wait on the limiter, not the combinators. With a burst
+	// of 1 at 100 per second the last of them is allowed after 110ms.
+	if elapsed < 100*time.Millisecond {
+		t.Errorf("expected the steps to be throttled to 100 per second, took %v", elapsed)
+	}
+}
+
+func TestExecutor_WithGlobalRateLimit_ChargesLeafStepsOnly(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// A burst of 12 covers the 12 leaf steps, any further wait would exceed the deadline.
+	rl := kyro.NewRateLimiterPer(1, time.Hour, 12)
+	executor := kyro.NewExecutor(ctx).WithGlobalRateLimit(rl)
+	defer executor.Cancel()
+
+	leaf := func(input any, err error) (any, error) {
+		return input, err
+	}
+
+	_, err := executor.Execute(kyro.InSequence(
+		leaf,
+		kyro.InParallel(leaf, leaf, kyro.InSequence(leaf, kyro.InParallel(leaf, leaf, leaf))),
+		kyro.RepeatStep(leaf, 4),
+		kyro.NamedStep("last", leaf),
+	))
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rl.Allow() {
+		t.Error("expected the 12 leaf steps to use up the burst of the limiter")
+	}
+}
+
+func TestExecutor_WithMaxConcurrency_NestedParallels(t *testing.T) {
+	var running, maxRunning, completed atomic.Int32
+
+	leaf := func(input any, err error) (any, error) {
+		current := running.Add(1)
+		for {
+			seen := maxRunning.Load()
+			if current <= seen || maxRunning.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		running.Add(-1)
+		completed.Add(1)
+		return input, nil
+	}
+
+	inner := func() kyro.PipelineStep {
+		return kyro.InParallel(leaf, leaf, leaf, leaf)
+	}
+	p := kyro.InParallel(
+		inner(),
+		inner(),
+		kyro.InSequence(inner(), kyro.InParallelCollect(leaf, leaf)),
+		kyro.InParallelQuorum(2, leaf, leaf, leaf),
+	)
+
+	executor := kyro.NewExecutor(context.Background()).WithMaxConcurrency(3)
+	defer executor.Cancel()
+
+	if _, err := executor.Execute(p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := maxRunning.Load(); n > 3 {
+		t.Errorf("expected at most 3 steps running at once, got %d", n)
+	}
+	if n := completed.Load(); n < 16 {
+		t.Errorf("expected at least 16 completed leaves, got %d", n)
+	}
+}
@@ -2,26 +2,102 @@ package kyro
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // ParallelFileProcessor represents a processor for reading and processing a file line by line in parallel.
 type ParallelFileProcessor struct {
 	filePath        string
+	filePaths       []string
+	reader          io.Reader
+	gzip            bool
 	numberOfWorkers int
 
 	processLineFunc ProcessFunc[[]byte]
+	transformFunc   func(line []byte) ([]byte, error)
+	outputPath      string
+	orderedOutput   bool
+	checkpointPath  string
+	resume          bool
+	splitFunc       bufio.SplitFunc
+	blockSize       int
+	rejectShort     bool
+	delimiter       byte
+	maxLineBytes    int
+	readBufferSize  int
+	shardKeyFunc    func(line []byte) []byte
 	processed       int
 	processedMutex  sync.Mutex
 
 	progressBatch int
 	progressFunc  ProgressNotifier
 
-	errorFunc ErrorNotifier[[]byte]
+	timedProgressInterval time.Duration
+	timedProgressFunc     ProgressNotifier
+
+	byteProgressFunc ByteProgressNotifier
+
+	errorFunc      ErrorNotifier[[]byte]
+	assignmentFunc func(workerID int, line []byte)
+
+	contextBefore int
+	contextAfter  int
+}
+
+// ErrLineTooLong is wrapped by the error reported for a line exceeding the limit set
+// with WithMaxLineBytes.
+var ErrLineTooLong = errors.New("line too long")
+
+// ErrShortBlock is wrapped by the error reported for a final block shorter than the block
+// size when WithRejectShortBlock is set.
+var ErrShortBlock = errors.New("short block")
+
+// LineError is the error reported to the error notifier for a line that failed to process.
+// It wraps the error returned for the line and describes where the line is in the input.
+type LineError struct {
+	Err  error
+	Line []byte
+	// Path is the path of the file the line was read from, empty when reading from a reader.
+	Path string
+	// Number is the 1-based number of the line in its file.
+	Number int
+	// Before and After are only set with WithErrorContext.
+	// Before holds up to the configured number of lines preceding the line, oldest first.
+	Before [][]byte
+	// After holds up to the configured number of lines following the line.
+	After [][]byte
+}
+
+func (e *LineError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// fileLine is a line handed to a worker together with its number and the lines surrounding it.
+// If err is set, the line could not be read completely and is reported without processing it.
+// number counts the lines of the file at path, while seq counts the lines across all files.
+type fileLine struct {
+	data   []byte
+	err    error
+	path   string
+	number int
+	seq    int
+	before [][]byte
+	after  [][]byte
 }
 
 // NewParallelFileProcessor creates a new ParallelFileProcessor with the specified number of workers.
@@ -29,6 +105,7 @@ func NewParallelFileProcessor(numberOfWorkers int) *ParallelFileProcessor {
 	return &ParallelFileProcessor{
 		numberOfWorkers: numberOfWorkers,
 		progressBatch:   100,
+		delimiter:       '\n',
 	}
 }
 
@@ -38,9 +115,171 @@ func (p *ParallelFileProcessor) WithFilePath(filePath string) *ParallelFileProce
 	return p
 }
 
+// WithFilePaths sets the paths to several files to be processed instead of the one set with
+// WithFilePath, e.g. the shards of a dataset. The files are read one after the other, in the
+// given order, and their lines are processed by the same workers. Progress is counted across
+// all files, and the LineError of a failed line carries the path of its file. A file that
+// cannot be opened ends the processing.
+func (p *ParallelFileProcessor) WithFilePaths(paths []string) *ParallelFileProcessor {
+	p.filePaths = paths
+	return p
+}
+
+// WithReader sets a stream, e.g. stdin, the body of an HTTP response or an in-memory buffer,
+// to be processed instead of a file. If both a reader and a file path are set, the reader
+// takes precedence. Combine it with WithGzip for a gzip compressed stream.
+func (p *ParallelFileProcessor) WithReader(r io.Reader) *ParallelFileProcessor {
+	p.reader = r
+	return p
+}
+
+// WithGzipReader sets a gzip compressed stream, e.g. the body of an HTTP response, to be
+// processed instead of a file. The stream is decompressed on the fly while it is split into
+// lines, so it never has to be staged on disk. Errors caused by a corrupt stream are
+// returned by Process.
+func (p *ParallelFileProcessor) WithGzipReader(r io.Reader) *ParallelFileProcessor {
+	p.reader = r
+	p.gzip = true
+	return p
+}
+
+// WithGzip marks the input as gzip compressed, so that it is decompressed on the fly while
+// it is split into lines. Files whose path ends in .gz are decompressed without it.
+func (p *ParallelFileProcessor) WithGzip() *ParallelFileProcessor {
+	p.gzip = true
+	return p
+}
+
 // OnProcessLine sets the function to be used for processing each line.
 func (p *ParallelFileProcessor) OnProcessLine(processLineFunc ProcessFunc[[]byte]) *ParallelFileProcessor {
 	p.processLineFunc = processLineFunc
+	p.transformFunc = nil
+	return p
+}
+
+// OnTransformLine sets a function transforming each line, whose non-nil results are written
+// to the file set with WithOutputPath, each followed by a newline. A line is dropped from the
+// output by returning nil. The output is written by a single goroutine, so lines never
+// interleave, but in no particular order unless WithOrderedOutput is set. It replaces the
+// function set with OnProcessLine.
+func (p *ParallelFileProcessor) OnTransformLine(transformFunc func(line []byte) ([]byte, error)) *ParallelFileProcessor {
+	p.transformFunc = transformFunc
+	p.processLineFunc = nil
+	return p
+}
+
+// WithOutputPath sets the path of the file the transformed lines are written to, see
+// OnTransformLine. The file is created or truncated when Process is called.
+func (p *ParallelFileProcessor) WithOutputPath(path string) *ParallelFileProcessor {
+	p.outputPath = path
+	return p
+}
+
+// WithCheckpoint makes the processor record the highest line number up to which all lines
+// have completed to the file at path, about once a second and when processing ends. Lines that
+// failed to process count as completed, as they are returned among the errored lines by Process.
+// With an output file set with WithOutputPath, a line only counts as completed once its output
+// has been flushed to the file, so the checkpoint never gets ahead of the output. Lines are
+// processed concurrently, so the checkpoint does not advance past a line still in progress,
+// even if later lines have completed. See Resume.
+func (p *ParallelFileProcessor) WithCheckpoint(path string) *ParallelFileProcessor {
+	p.checkpointPath = path
+	return p
+}
+
+// Resume makes Process skip the lines at or below the line number recorded in the checkpoint
+// file set with WithCheckpoint, so that a restarted job continues where the previous one left
+// off. Without a checkpoint file all lines are processed. When resuming, the output file set
+// with WithOutputPath is appended to instead of truncated. Lines that completed after the
+// checkpoint was last written are processed again.
+func (p *ParallelFileProcessor) Resume() *ParallelFileProcessor {
+	p.resume = true
+	return p
+}
+
+// WithOrderedOutput makes the transformed lines be written in the order of the input lines.
+// Lines completing ahead of an earlier line are buffered until the earlier line completed.
+func (p *ParallelFileProcessor) WithOrderedOutput() *ParallelFileProcessor {
+	p.orderedOutput = true
+	return p
+}
+
+// WithSplitFunc sets the function used to split the file into tokens. Every token
+// produced by splitFunc is handed to the process function as if it were a line, so
+// splitFunc can implement arbitrary tokenization such as bufio.ScanWords or a custom
+// record framing. Tokens are limited to bufio.MaxScanTokenSize bytes, or the limit set with
+// WithMaxLineBytes; a longer token stops the processing with bufio.ErrTooLong.
+func (p *ParallelFileProcessor) WithSplitFunc(splitFunc bufio.SplitFunc) *ParallelFileProcessor {
+	p.splitFunc = splitFunc
+	return p
+}
+
+// WithBlockSize makes the processor read the input in blocks of n bytes instead of lines,
+// for binary records of a fixed size. Every block is handed to the process function as if it
+// were a line. The final block is shorter if the input size is not a multiple of n; it is
+// processed like any other block unless WithRejectShortBlock is set. It takes precedence over
+// a split function and the delimiter.
+func (p *ParallelFileProcessor) WithBlockSize(n int) *ParallelFileProcessor {
+	p.blockSize = n
+	return p
+}
+
+// WithRejectShortBlock makes the processor report a final block shorter than the block size
+// set with WithBlockSize as errored with an error wrapping ErrShortBlock, instead of
+// processing it.
+func (p *ParallelFileProcessor) WithRejectShortBlock() *ParallelFileProcessor {
+	p.rejectShort = true
+	return p
+}
+
+// WithDelimiter sets the byte terminating every line, e.g. '\x00' for NUL-separated records.
+// The delimiter defaults to '\n' and is stripped from the lines handed to the process
+// function. It has no effect if a split function is set.
+func (p *ParallelFileProcessor) WithDelimiter(delim byte) *ParallelFileProcessor {
+	p.delimiter = delim
+	return p
+}
+
+// WithMaxLineBytes limits lines to n bytes, excluding the delimiter, so that a pathological
+// input without delimiters cannot exhaust the memory. A longer line is not processed, but
+// reported as errored with an error wrapping ErrLineTooLong, holding only its first n bytes.
+// With a split function, a longer token stops the processing with bufio.ErrTooLong instead.
+func (p *ParallelFileProcessor) WithMaxLineBytes(n int) *ParallelFileProcessor {
+	p.maxLineBytes = n
+	return p
+}
+
+// WithReadBufferSize sets the size of the buffer used for reading the input, which can be
+// tuned for throughput. It defaults to 4096 bytes.
+func (p *ParallelFileProcessor) WithReadBufferSize(n int) *ParallelFileProcessor {
+	p.readBufferSize = n
+	return p
+}
+
+// WithLineShardKey routes every line to a worker determined by a hash of the key extracted
+// by keyFunc, instead of handing it to the next idle worker. All lines sharing a key are
+// therefore processed by the same worker, in the order they appear in the input, which
+// allows per-key state such as session aggregation within a single pass. A worker that
+// is slow on one key holds back all other keys routed to it.
+func (p *ParallelFileProcessor) WithLineShardKey(keyFunc func(line []byte) []byte) *ParallelFileProcessor {
+	p.shardKeyFunc = keyFunc
+	return p
+}
+
+// WithWorkerAssignmentNotifier sets a function that is called by a worker right before
+// it processes a line. Workers are identified by an id in [0, numberOfWorkers).
+func (p *ParallelFileProcessor) WithWorkerAssignmentNotifier(assignmentFunc func(workerID int, line []byte)) *ParallelFileProcessor {
+	p.assignmentFunc = assignmentFunc
+	return p
+}
+
+// WithErrorContext makes the processor capture up to before lines preceding and up to after
+// lines following every line, and report them in the *LineError passed to the error notifier
+// when the line fails to process. This helps debugging lines whose meaning depends on their
+// neighbors. The reader keeps the last before lines and reads after lines ahead to do so.
+func (p *ParallelFileProcessor) WithErrorContext(before, after int) *ParallelFileProcessor {
+	p.contextBefore = max(before, 0)
+	p.contextAfter = max(after, 0)
 	return p
 }
 
@@ -52,8 +291,29 @@ func (p *ParallelFileProcessor) WithProgressNotifier(batch int, progressFunc Pro
 	return p
 }
 
+// WithTimedProgressNotifier sets a progress notification function that is called once per
+// interval of wall-clock time, which gives steady updates no matter how long the lines take,
+// and a final time once the processing completed. It can be combined with WithProgressNotifier.
+func (p *ParallelFileProcessor) WithTimedProgressNotifier(interval time.Duration, progressFunc ProgressNotifier) *ParallelFileProcessor {
+	p.timedProgressInterval = interval
+	p.timedProgressFunc = progressFunc
+	return p
+}
+
+// WithByteProgressNotifier sets a function notified of the number of bytes read from the
+// input relative to the size of the input files, which reflects the progress better than the
+// number of lines when line lengths vary widely. For compressed files, the compressed bytes
+// are counted. The function is called by the reading goroutine whenever the percentage grows
+// by at least a whole percent, and once all input is read. The size of a reader is not known.
+// It can be combined with the other progress notifiers.
+func (p *ParallelFileProcessor) WithByteProgressNotifier(progressFunc ByteProgressNotifier) *ParallelFileProcessor {
+	p.byteProgressFunc = progressFunc
+	return p
+}
+
 // WithErrorNotifier sets the error notification function.
-// errorFunc is the function to call when an error occurs during processing.
+// errorFunc is the function to call when an error occurs during processing. The error of a
+// line that failed to process is a *LineError, which also carries the number of the line.
 func (p *ParallelFileProcessor) WithErrorNotifier(errorFunc ErrorNotifier[[]byte]) *ParallelFileProcessor {
 	p.errorFunc = errorFunc
 	return p
@@ -68,48 +328,115 @@ func (p *ParallelFileProcessor) Process() (*[][]byte, error) {
 		return &erroredLines, fmt.Errorf("number of workers must be positive")
 	}
 
-	if p.filePath == "" {
-		return &erroredLines, fmt.Errorf("file path must be set")
+	if p.filePath == "" && len(p.filePaths) == 0 && p.reader == nil {
+		return &erroredLines, fmt.Errorf("file path or reader must be set")
 	}
 
-	if p.processLineFunc == nil {
+	if p.processLineFunc == nil && p.transformFunc == nil {
 		return &erroredLines, fmt.Errorf("process line function must be set")
 	}
 
-	file, err := os.Open(p.filePath)
-	if err != nil {
-		return &erroredLines, fmt.Errorf("failed to open file: %w", err)
+	if p.transformFunc != nil && p.outputPath == "" {
+		return &erroredLines, fmt.Errorf("output path must be set to transform lines")
+	}
+
+	if p.resume && p.checkpointPath == "" {
+		return &erroredLines, fmt.Errorf("checkpoint path must be set to resume")
 	}
-	defer file.Close()
 
-	lineCh := make(chan []byte, p.numberOfWorkers)
+	// skip is the number of leading lines completed by a previous run.
+	var skip int
+	if p.resume {
+		var err error
+		if skip, err = readCheckpoint(p.checkpointPath); err != nil {
+			return &erroredLines, err
+		}
+	}
+
+	var tracker *checkpoint
+	if p.checkpointPath != "" {
+		tracker = newCheckpoint(p.checkpointPath, skip)
+	}
+
+	var outCh chan<- outputLine
+	finishOutput := func() error { return nil }
+	if p.transformFunc != nil {
+		var err error
+		outCh, finishOutput, err = p.startOutput(skip, tracker)
+		if err != nil {
+			return &erroredLines, err
+		}
+	}
+
+	// Without a shard key all workers share one channel and pick up lines as they
+	// become idle. With a shard key every worker gets a channel of its own.
+	lineCh := make(chan fileLine, p.numberOfWorkers)
+	workerChs := make([]chan fileLine, p.numberOfWorkers)
+	for i := range workerChs {
+		workerChs[i] = lineCh
+		if p.shardKeyFunc != nil {
+			workerChs[i] = make(chan fileLine, 1)
+		}
+	}
+
+	// errCh is drained into erroredLines while the workers are running, so
+	// that no errored line is lost however many of them there are.
 	errCh := make(chan []byte, p.numberOfWorkers)
+	collected := make(chan struct{})
+
+	go func() {
+		defer close(collected)
+		for errLine := range errCh {
+			erroredLines = append(erroredLines, errLine)
+		}
+	}()
 
 	var wg sync.WaitGroup
 	wg.Add(p.numberOfWorkers)
 
 	startTime := time.Now()
 
-	worker := func() {
+	stopCheckpoint := tracker.start(checkpointInterval)
+
+	stopTimedProgress := startTimedProgress(p.timedProgressInterval, p.timedProgressFunc, startTime, func() int {
+		p.processedMutex.Lock()
+		defer p.processedMutex.Unlock()
+		return p.processed
+	})
+
+	worker := func(workerID int, lines <-chan fileLine) {
 		defer wg.Done()
-		for line := range lineCh {
-			if err := p.processLineFunc(line); err != nil {
-				select {
-				// Attempt to send the errored line to the error channel.
-				case errCh <- line:
-					if p.errorFunc != nil {
-						p.errorFunc(err, line)
-					}
-				// If the error channel is full, we report this as an error
-				// before attempting to report the original processing error.
-				default:
-					if p.errorFunc != nil {
-						p.errorFunc(fmt.Errorf("error channel is full"), line)
-						p.errorFunc(err, line)
-					}
+		for next := range lines {
+			line := next.data
+
+			if p.assignmentFunc != nil {
+				p.assignmentFunc(workerID, line)
+			}
+
+			var output []byte
+			err := next.err
+			if err == nil {
+				output, err = p.process(line)
+			}
+
+			if err != nil {
+				err = &LineError{Err: err, Line: line, Path: next.path, Number: next.number, Before: next.before, After: next.after}
+
+				errCh <- line
+				if p.errorFunc != nil {
+					p.errorFunc(err, line)
 				}
 			}
 
+			// With ordered output the writer has to learn about every line, including
+			// the ones without output, as it waits for each line in turn. A line handed
+			// to the writer is marked as completed by the writer once it is flushed.
+			if outCh != nil && (output != nil || p.orderedOutput) {
+				outCh <- outputLine{number: next.seq, data: output}
+			} else {
+				tracker.complete(next.seq)
+			}
+
 			p.processedMutex.Lock()
 			p.processed++
 			currentProcessed := p.processed
@@ -123,39 +450,62 @@ func (p *ParallelFileProcessor) Process() (*[][]byte, error) {
 		}
 	}
 
-	for range p.numberOfWorkers {
-		go worker()
+	for workerID, lines := range workerChs {
+		go worker(workerID, lines)
 	}
 
-	go func() {
-		reader := bufio.NewReader(file)
-
-		for {
-			lineBytes, err := reader.ReadBytes('\n')
-
-			if err != nil {
-				if err == io.EOF {
-					break
-				}
+	// readErr is only written by the reading goroutine before it closes the worker
+	// channels, so it is safe to read once all workers have finished. It is returned
+	// as is, as it already tells whether opening or reading the input failed.
+	var readErr error
 
-				fmt.Fprintf(os.Stderr, "read error: %v\n", err)
-				break
+	go func() {
+		defer func() {
+			if p.shardKeyFunc == nil {
+				close(lineCh)
+				return
 			}
 
-			if len(lineBytes) > 0 && lineBytes[len(lineBytes)-1] == '\n' {
-				lineBytes = lineBytes[:len(lineBytes)-1]
+			for _, ch := range workerChs {
+				close(ch)
+			}
+		}()
+
+		paths := p.inputPaths()
+		progress := p.newByteProgress(paths)
+		defer progress.finish()
+
+		var seq int
+		for _, path := range paths {
+			if readErr = p.readFile(path, progress, func(line fileLine) {
+				seq++
+				if line.seq = seq; line.seq <= skip {
+					return
+				}
+				workerChs[p.shardOf(line.data)] <- line
+			}); readErr != nil {
+				return
 			}
-
-			lineCh <- lineBytes
 		}
-		close(lineCh)
 	}()
 
 	wg.Wait()
+	stopTimedProgress()
+	writeErr := finishOutput()
+	checkpointErr := stopCheckpoint()
 	close(errCh)
+	<-collected
 
-	for errLine := range errCh {
-		erroredLines = append(erroredLines, errLine)
+	if readErr != nil {
+		return &erroredLines, readErr
+	}
+
+	if writeErr != nil {
+		return &erroredLines, fmt.Errorf("failed to write output: %w", writeErr)
+	}
+
+	if checkpointErr != nil {
+		return &erroredLines, fmt.Errorf("failed to write checkpoint: %w", checkpointErr)
 	}
 
 	if len(erroredLines) > 0 {
@@ -164,3 +514,465 @@ func (p *ParallelFileProcessor) Process() (*[][]byte, error) {
 
 	return &erroredLines, nil
 }
+
+// process runs the process or transform function for line and returns the output of the
+// transform function. A panic raised by either function is recovered and returned as an
+// error wrapping ErrItemPanic, so that a single bad line cannot take down the whole processing.
+func (p *ParallelFileProcessor) process(line []byte) (output []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			output, err = nil, fmt.Errorf("%w: %v", ErrItemPanic, r)
+		}
+	}()
+
+	if p.transformFunc != nil {
+		output, err = p.transformFunc(line)
+		if err != nil {
+			return nil, err
+		}
+		return output, nil
+	}
+
+	return nil, p.processLineFunc(line)
+}
+
+// outputLine is a transformed line on its way to the output file. A nil data is not written.
+type outputLine struct {
+	number int
+	data   []byte
+}
+
+// startOutput creates the output file, or opens it for appending when resuming, and starts
+// the goroutine writing the lines sent to the returned channel to it. skip is the number of
+// leading lines not processed, which ordered output does not wait for. The lines are marked as
+// completed in tracker once they are flushed to the file, which happens once per checkpoint
+// interval while there is a tracker. The returned finish function closes the channel, waits
+// until all lines are written and the file is closed, and returns the first error that occurred.
+func (p *ParallelFileProcessor) startOutput(skip int, tracker *checkpoint) (chan<- outputLine, func() error, error) {
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if p.resume {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(p.outputPath, flag, 0o666)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	outCh := make(chan outputLine, p.numberOfWorkers)
+	done := make(chan error, 1)
+
+	go func() {
+		writer := bufio.NewWriter(file)
+
+		// writeErr is the first error writing the output. Once it is set, the
+		// remaining lines are still drained, so that no worker blocks forever.
+		var writeErr error
+
+		// unflushed holds the numbers of the lines written since the last flush.
+		var unflushed []int
+
+		write := func(number int, data []byte) {
+			unflushed = append(unflushed, number)
+			if writeErr != nil || data == nil {
+				return
+			}
+			if _, writeErr = writer.Write(data); writeErr == nil {
+				writeErr = writer.WriteByte('\n')
+			}
+		}
+
+		// flush writes the buffered lines to the file and only then marks them as
+		// completed, so that the checkpoint never covers a line lost in the buffer.
+		flush := func() {
+			if writeErr == nil {
+				writeErr = writer.Flush()
+			}
+			if writeErr == nil {
+				for _, number := range unflushed {
+					tracker.complete(number)
+				}
+			}
+			unflushed = unflushed[:0]
+		}
+
+		var flushTick <-chan time.Time
+		if tracker != nil {
+			ticker := time.NewTicker(checkpointInterval)
+			defer ticker.Stop()
+			flushTick = ticker.C
+		}
+
+		// pending holds the lines completed ahead of the next line in order.
+		nextNumber := skip + 1
+		pending := make(map[int][]byte)
+
+	loop:
+		for {
+			select {
+			case line, ok := <-outCh:
+				if !ok {
+					break loop
+				}
+
+				if !p.orderedOutput {
+					write(line.number, line.data)
+					continue
+				}
+
+				pending[line.number] = line.data
+				for {
+					data, ok := pending[nextNumber]
+					if !ok {
+						break
+					}
+
+					delete(pending, nextNumber)
+					write(nextNumber, data)
+					nextNumber++
+				}
+			case <-flushTick:
+				flush()
+			}
+		}
+
+		flush()
+		if closeErr := file.Close(); writeErr == nil {
+			writeErr = closeErr
+		}
+		done <- writeErr
+	}()
+
+	return outCh, func() error {
+		close(outCh)
+		return <-done
+	}, nil
+}
+
+// inputPaths returns the paths of the files to read in order. When reading from a reader, it
+// returns a single empty path standing in for the reader.
+func (p *ParallelFileProcessor) inputPaths() []string {
+	if p.reader != nil {
+		return []string{""}
+	}
+	if len(p.filePaths) > 0 {
+		return p.filePaths
+	}
+	return []string{p.filePath}
+}
+
+// readFile reads the lines of the file at path, or of the reader, and hands each of them to
+// emit, annotated with the path and their number in the file.
+func (p *ParallelFileProcessor) readFile(path string, progress *byteProgress, emit func(line fileLine)) error {
+	input, closeInput, err := p.open(path, progress)
+	if err != nil {
+		return err
+	}
+	defer closeInput()
+
+	annotated, flush := p.annotate(path, emit)
+	err = p.readLines(input, annotated)
+	flush()
+
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	return nil
+}
+
+// annotate returns an emit function for readLines that numbers every line in the order it is
+// read and attaches the lines surrounding it as configured with WithErrorContext, before
+// handing it to emit. Lines waiting for the lines following them are held back until flush
+// is called at the end of the input.
+func (p *ParallelFileProcessor) annotate(path string, emit func(line fileLine)) (func(line []byte, err error), func()) {
+	var number int
+	var recent [][]byte
+	var pending []*fileLine
+
+	flush := func() {
+		for _, waiting := range pending {
+			emit(*waiting)
+		}
+		pending = nil
+	}
+
+	return func(line []byte, err error) {
+		for _, waiting := range pending {
+			waiting.after = append(waiting.after, line)
+		}
+
+		for len(pending) > 0 && len(pending[0].after) == p.contextAfter {
+			emit(*pending[0])
+			pending = pending[1:]
+		}
+
+		number++
+		next := &fileLine{data: line, err: err, path: path, number: number}
+		if p.contextBefore > 0 {
+			next.before = slices.Clone(recent)
+			recent = append(recent, line)
+			if len(recent) > p.contextBefore {
+				recent = recent[1:]
+			}
+		}
+
+		if p.contextAfter == 0 {
+			emit(*next)
+			return
+		}
+		pending = append(pending, next)
+	}, flush
+}
+
+// shardOf returns the index of the worker the line is routed to. Without a shard key
+// every line is routed to the first channel, which is shared by all workers.
+func (p *ParallelFileProcessor) shardOf(line []byte) int {
+	if p.shardKeyFunc == nil {
+		return 0
+	}
+
+	hash := fnv.New64a()
+	hash.Write(p.shardKeyFunc(line))
+	return int(hash.Sum64() % uint64(p.numberOfWorkers))
+}
+
+// open returns the input to read the lines from together with a function releasing it.
+// The input is the configured reader if one is set and the file at path otherwise,
+// wrapped in a gzip reader if the input is compressed or the path ends in .gz. The bytes
+// read before decompression are counted by progress.
+func (p *ParallelFileProcessor) open(path string, progress *byteProgress) (io.Reader, func(), error) {
+	input, closeInput := p.reader, func() {}
+
+	if input == nil {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open file: %w", err)
+		}
+
+		input, closeInput = file, func() { file.Close() }
+	}
+	input = progress.reader(input)
+
+	if !p.gzip && (p.reader != nil || !strings.HasSuffix(path, ".gz")) {
+		return input, closeInput, nil
+	}
+
+	gzipReader, err := gzip.NewReader(input)
+	if err != nil {
+		closeInput()
+		return nil, nil, fmt.Errorf("failed to open gzip stream: %w", err)
+	}
+
+	return gzipReader, func() {
+		gzipReader.Close()
+		closeInput()
+	}, nil
+}
+
+// readLines splits r into lines and hands each of them to emit, together with an error if
+// the line is too long to be processed. It uses the split function when one is set and falls
+// back to reading delimiter-terminated lines otherwise.
+func (p *ParallelFileProcessor) readLines(r io.Reader, emit func(line []byte, err error)) error {
+	bufferSize := p.readBufferSize
+	if bufferSize <= 0 {
+		bufferSize = 4096
+	}
+
+	if p.blockSize > 0 {
+		return p.readBlocks(bufio.NewReaderSize(r, bufferSize), emit)
+	}
+
+	if p.splitFunc != nil {
+		scanner := bufio.NewScanner(r)
+		scanner.Split(p.splitFunc)
+
+		// The buffer of the scanner has to hold the terminator of a token as well,
+		// so it leaves room for a terminator of up to utf8.UTFMax bytes, e.g. "\r\n".
+		maxTokenSize := bufio.MaxScanTokenSize
+		if p.maxLineBytes > 0 {
+			maxTokenSize = p.maxLineBytes + utf8.UTFMax
+		}
+		scanner.Buffer(make([]byte, 0, min(bufferSize, maxTokenSize)), maxTokenSize)
+
+		for scanner.Scan() {
+			if p.maxLineBytes > 0 && len(scanner.Bytes()) > p.maxLineBytes {
+				return bufio.ErrTooLong
+			}
+
+			// The scanner reuses its buffer between calls to Scan, so the token
+			// has to be copied before it is handed to a worker.
+			emit(bytes.Clone(scanner.Bytes()), nil)
+		}
+
+		return scanner.Err()
+	}
+
+	reader := bufio.NewReaderSize(r, bufferSize)
+
+	for {
+		lineBytes, tooLong, err := p.readLine(reader)
+
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		if err == io.EOF && len(lineBytes) == 0 {
+			return nil
+		}
+
+		switch {
+		case tooLong:
+			emit(lineBytes, fmt.Errorf("%w: exceeds %d bytes", ErrLineTooLong, p.maxLineBytes))
+		case err == io.EOF:
+			// The last line of an input that does not end with the delimiter.
+			emit(lineBytes, nil)
+		default:
+			emit(lineBytes[:len(lineBytes)-1], nil)
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// readBlocks splits r into blocks of the block size and hands each of them to emit.
+func (p *ParallelFileProcessor) readBlocks(r io.Reader, emit func(line []byte, err error)) error {
+	for {
+		// Every block gets a buffer of its own, as it is handed to a worker.
+		block := make([]byte, p.blockSize)
+		n, err := io.ReadFull(r, block)
+
+		switch {
+		case err == io.EOF:
+			return nil
+		case err == io.ErrUnexpectedEOF:
+			if p.rejectShort {
+				emit(block[:n], fmt.Errorf("%w: %d of %d bytes", ErrShortBlock, n, p.blockSize))
+			} else {
+				emit(block[:n], nil)
+			}
+			return nil
+		case err != nil:
+			return err
+		}
+
+		emit(block, nil)
+	}
+}
+
+// readLine reads the next delimiter-terminated line from reader, including the delimiter.
+// At the end of the input, it returns io.EOF along with the remaining bytes, which form the
+// last line if the input does not end with the delimiter. A line exceeding the limit set with
+// WithMaxLineBytes is read to its end, but only its first maxLineBytes bytes are kept and
+// returned without the delimiter.
+func (p *ParallelFileProcessor) readLine(reader *bufio.Reader) (line []byte, tooLong bool, err error) {
+	for {
+		chunk, err := reader.ReadSlice(p.delimiter)
+
+		if !tooLong {
+			line = append(line, chunk...)
+
+			length := len(line)
+			if err == nil {
+				length--
+			}
+
+			if p.maxLineBytes > 0 && length > p.maxLineBytes {
+				tooLong = true
+				line = line[:p.maxLineBytes]
+			}
+		}
+
+		if err != bufio.ErrBufferFull {
+			return line, tooLong, err
+		}
+	}
+}
+
+// byteProgress counts the bytes read from the input and notifies the byte progress function.
+type byteProgress struct {
+	notify ByteProgressNotifier
+	total  int64
+	read   int64
+	// notified is the percentage of the last notification, -1 before the first one.
+	notified int
+}
+
+// newByteProgress creates a byteProgress for the files at paths, whose total size is taken
+// from os.Stat. The total is 0 when reading from a reader or if a file cannot be stat'ed.
+// Without a byte progress function it returns nil, which counts nothing.
+func (p *ParallelFileProcessor) newByteProgress(paths []string) *byteProgress {
+	if p.byteProgressFunc == nil {
+		return nil
+	}
+
+	progress := &byteProgress{notify: p.byteProgressFunc, notified: -1}
+	if p.reader != nil {
+		return progress
+	}
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			progress.total = 0
+			break
+		}
+		progress.total += info.Size()
+	}
+	return progress
+}
+
+// reader returns a reader counting the bytes read from r. On a nil byteProgress it returns r.
+func (b *byteProgress) reader(r io.Reader) io.Reader {
+	if b == nil {
+		return r
+	}
+	return &countingReader{r: r, count: b.add}
+}
+
+// add counts n more bytes read and notifies if the percentage grew by a whole percent.
+// Without a known total, every read is notified.
+func (b *byteProgress) add(n int) {
+	if n <= 0 {
+		return
+	}
+
+	b.read += int64(n)
+	if b.total <= 0 || int(b.percent()) > b.notified {
+		b.report()
+	}
+}
+
+// finish notifies the final progress once all input is read. It is a no-op on a nil byteProgress.
+func (b *byteProgress) finish() {
+	if b != nil {
+		b.report()
+	}
+}
+
+func (b *byteProgress) report() {
+	pct := b.percent()
+	b.notified = int(pct)
+	b.notify(b.read, b.total, pct)
+}
+
+func (b *byteProgress) percent() float64 {
+	if b.total <= 0 {
+		return 0
+	}
+	return min(float64(b.read)/float64(b.total)*100, 100)
+}
+
+// countingReader is an io.Reader calling count with the number of bytes of every read.
+type countingReader struct {
+	r     io.Reader
+	count func(n int)
+}
+
+// Read implements io.Reader.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.count(n)
+	return n, err
+}
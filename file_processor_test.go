@@ -0,0 +1,952 @@
+package kyro_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/loggdme/kyro"
+)
+
+/* ====== Helper Functions ====== */
+
+func writeTempFile(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "input.txt")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+
+	return path
+}
+
+/* ====== Test Cases ====== */
+
+func TestParallelFileProcessor_WithSplitFunc_ScanWords(t *testing.T) {
+	path := writeTempFile(t, "alpha beta\ngamma\tdelta  epsilon\n")
+
+	var processed []string
+	var mu sync.Mutex
+
+	erroredLines, err := kyro.NewParallelFileProcessor(3).
+		WithFilePath(path).
+		WithSplitFunc(bufio.ScanWords).
+		OnProcessLine(func(line []byte) error {
+			mu.Lock()
+			processed = append(processed, string(line))
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(*erroredLines) != 0 {
+		t.Errorf("expected empty errored lines, got %v", *erroredLines)
+	}
+
+	slices.Sort(processed)
+	expected := []string{"alpha", "beta", "delta", "epsilon", "gamma"}
+	if !slices.Equal(processed, expected) {
+		t.Errorf("expected words %v, got %v", expected, processed)
+	}
+}
+
+func TestParallelFileProcessor_WithSplitFunc_TokenTooLong(t *testing.T) {
+	path := writeTempFile(t, string(make([]byte, bufio.MaxScanTokenSize+1)))
+
+	_, err := kyro.NewParallelFileProcessor(1).
+		WithFilePath(path).
+		WithSplitFunc(bufio.ScanLines).
+		OnProcessLine(func(line []byte) error { return nil }).
+		Process()
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("expected bufio.ErrTooLong, got: %v", err)
+	}
+}
+
+func TestParallelFileProcessor_WithSplitFunc_WithMaxLineBytes(t *testing.T) {
+	var processed []string
+
+	_, err := kyro.NewParallelFileProcessor(1).
+		WithReader(strings.NewReader("abcd\nab\r\nabcd")).
+		WithSplitFunc(bufio.ScanLines).
+		WithMaxLineBytes(4).
+		OnProcessLine(func(line []byte) error {
+			processed = append(processed, string(line))
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := []string{"abcd", "ab", "abcd"}; !slices.Equal(processed, expected) {
+		t.Errorf("expected tokens %v, got %v", expected, processed)
+	}
+
+	_, err = kyro.NewParallelFileProcessor(1).
+		WithReader(strings.NewReader("abcd\nabcde\n")).
+		WithSplitFunc(bufio.ScanLines).
+		WithMaxLineBytes(4).
+		OnProcessLine(func(line []byte) error { return nil }).
+		Process()
+
+	if !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("expected bufio.ErrTooLong for a token of 5 bytes, got: %v", err)
+	}
+}
+
+func gzipBytes(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write gzip content: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("failed to close gzip writer: %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestParallelFileProcessor_WithReader(t *testing.T) {
+	var processed []string
+	var mu sync.Mutex
+
+	_, err := kyro.NewParallelFileProcessor(3).
+		WithFilePath(filepath.Join(t.TempDir(), "does-not-exist.txt")).
+		WithReader(strings.NewReader("alpha\nbeta\ngamma\ndelta\n")).
+		OnProcessLine(func(line []byte) error {
+			mu.Lock()
+			processed = append(processed, string(line))
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	slices.Sort(processed)
+	if expected := []string{"alpha", "beta", "delta", "gamma"}; !slices.Equal(processed, expected) {
+		t.Errorf("expected lines %v, got %v", expected, processed)
+	}
+}
+
+func TestParallelFileProcessor_LastLineWithoutDelimiter(t *testing.T) {
+	var processed []string
+
+	_, err := kyro.NewParallelFileProcessor(1).
+		WithReader(strings.NewReader("a\nb")).
+		OnProcessLine(func(line []byte) error {
+			processed = append(processed, string(line))
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if expected := []string{"a", "b"}; !slices.Equal(processed, expected) {
+		t.Errorf("expected lines %v, got %v", expected, processed)
+	}
+
+	erroredLines, err := kyro.NewParallelFileProcessor(1).
+		WithReader(strings.NewReader("a\x00toolong")).
+		WithDelimiter(0).
+		WithMaxLineBytes(4).
+		OnProcessLine(func(line []byte) error { return nil }).
+		Process()
+
+	if err == nil || len(*erroredLines) != 1 || string((*erroredLines)[0]) != "tool" {
+		t.Errorf("expected the last record to be reported as too long, got %q: %v", *erroredLines, err)
+	}
+}
+
+func TestParallelFileProcessor_WithDelimiter(t *testing.T) {
+	path := writeTempFile(t, "first record\nwith newline\x00second\x00\x00third\x00")
+
+	var processed []string
+	var mu sync.Mutex
+
+	_, err := kyro.NewParallelFileProcessor(2).
+		WithFilePath(path).
+		WithDelimiter('\x00').
+		OnProcessLine(func(line []byte) error {
+			mu.Lock()
+			processed = append(processed, string(line))
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	slices.Sort(processed)
+	if expected := []string{"", "first record\nwith newline", "second", "third"}; !slices.Equal(processed, expected) {
+		t.Errorf("expected records %q, got %q", expected, processed)
+	}
+}
+
+func TestParallelFileProcessor_WithGzipReader(t *testing.T) {
+	compressed := gzipBytes(t, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+
+	var processed []string
+	var mu sync.Mutex
+
+	_, err := kyro.NewParallelFileProcessor(2).
+		WithGzipReader(bytes.NewReader(compressed)).
+		OnProcessLine(func(line []byte) error {
+			mu.Lock()
+			processed = append(processed, string(line))
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	slices.Sort(processed)
+	expected := []string{"{\"id\":1}", "{\"id\":2}", "{\"id\":3}"}
+	if !slices.Equal(processed, expected) {
+		t.Errorf("expected lines %v, got %v", expected, processed)
+	}
+}
+
+func TestParallelFileProcessor_WithGzipReader_CorruptStream(t *testing.T) {
+	compressed := gzipBytes(t, strings.Repeat("some line\n", 1000))
+	corrupted := compressed[:len(compressed)/2]
+
+	_, err := kyro.NewParallelFileProcessor(2).
+		WithGzipReader(bytes.NewReader(corrupted)).
+		OnProcessLine(func(line []byte) error { return nil }).
+		Process()
+
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got: %v", err)
+	}
+}
+
+func TestParallelFileProcessor_WithGzipReader_NotGzip(t *testing.T) {
+	_, err := kyro.NewParallelFileProcessor(2).
+		WithGzipReader(strings.NewReader("plain text\n")).
+		OnProcessLine(func(line []byte) error { return nil }).
+		Process()
+
+	if !errors.Is(err, gzip.ErrHeader) {
+		t.Errorf("expected gzip.ErrHeader, got: %v", err)
+	}
+}
+
+func TestParallelFileProcessor_GzipFile(t *testing.T) {
+	compressed := gzipBytes(t, "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n")
+	dir := t.TempDir()
+
+	detected := filepath.Join(dir, "input.jsonl.gz")
+	explicit := filepath.Join(dir, "input.jsonl.compressed")
+	for _, path := range []string{detected, explicit} {
+		if err := os.WriteFile(path, compressed, 0o644); err != nil {
+			t.Fatalf("failed to write gzip fixture: %v", err)
+		}
+	}
+
+	for name, processor := range map[string]*kyro.ParallelFileProcessor{
+		"extension": kyro.NewParallelFileProcessor(2).WithFilePath(detected),
+		"WithGzip":  kyro.NewParallelFileProcessor(2).WithFilePath(explicit).WithGzip(),
+	} {
+		var processed []string
+		var mu sync.Mutex
+
+		_, err := processor.
+			OnProcessLine(func(line []byte) error {
+				mu.Lock()
+				processed = append(processed, string(line))
+				mu.Unlock()
+				return nil
+			}).
+			Process()
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+
+		slices.Sort(processed)
+		expected := []string{"{\"id\":1}", "{\"id\":2}", "{\"id\":3}"}
+		if !slices.Equal(processed, expected) {
+			t.Errorf("%s: expected lines %v, got %v", name, expected, processed)
+		}
+	}
+}
+
+func TestParallelFileProcessor_WithLineShardKey(t *testing.T) {
+	var content strings.Builder
+	for i := range 200 {
+		fmt.Fprintf(&content, "session-%d,%d\n", i%7, i)
+	}
+	path := writeTempFile(t, content.String())
+
+	workersByKey := make(map[string]map[int]bool)
+	lastByKey := make(map[string]int)
+	var mu sync.Mutex
+
+	_, err := kyro.NewParallelFileProcessor(4).
+		WithFilePath(path).
+		WithLineShardKey(func(line []byte) []byte {
+			key, _, _ := bytes.Cut(line, []byte(","))
+			return key
+		}).
+		WithWorkerAssignmentNotifier(func(workerID int, line []byte) {
+			key, _, _ := strings.Cut(string(line), ",")
+
+			mu.Lock()
+			if workersByKey[key] == nil {
+				workersByKey[key] = make(map[int]bool)
+			}
+			workersByKey[key][workerID] = true
+			mu.Unlock()
+		}).
+		OnProcessLine(func(line []byte) error {
+			key, value, _ := strings.Cut(string(line), ",")
+			n, _ := strconv.Atoi(value)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if last, ok := lastByKey[key]; ok && last > n {
+				t.Errorf("expected lines of key %s in input order, got %d after %d", key, n, last)
+			}
+			lastByKey[key] = n
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(workersByKey) != 7 {
+		t.Errorf("expected 7 keys, got %d", len(workersByKey))
+	}
+	for key, workers := range workersByKey {
+		if len(workers) != 1 {
+			t.Errorf("expected key %s to be processed by one worker, got %v", key, workers)
+		}
+	}
+}
+
+func TestParallelFileProcessor_RecoversPanic(t *testing.T) {
+	path := writeTempFile(t, "one\ntwo\nthree\nfour\n")
+
+	var mu sync.Mutex
+	var processed []string
+
+	erroredLines, err := kyro.NewParallelFileProcessor(2).
+		WithFilePath(path).
+		OnProcessLine(func(line []byte) error {
+			if string(line) == "three" {
+				panic("unparsable line")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, string(line))
+			return nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if len(*erroredLines) != 1 || string((*erroredLines)[0]) != "three" {
+		t.Errorf("expected the panicking line to be errored, got %q", *erroredLines)
+	}
+	if slices.Sort(processed); !slices.Equal(processed, []string{"four", "one", "two"}) {
+		t.Errorf("expected the other lines to be processed, got %v", processed)
+	}
+}
+
+func TestParallelFileProcessor_WithErrorContext(t *testing.T) {
+	path := writeTempFile(t, "l1\nl2\nl3\nbad\nl5\nl6\nl7\n")
+
+	var lineErr *kyro.LineError
+	var mu sync.Mutex
+
+	erroredLines, err := kyro.NewParallelFileProcessor(2).
+		WithFilePath(path).
+		WithErrorContext(2, 1).
+		WithErrorNotifier(func(err error, line []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			errors.As(err, &lineErr)
+		}).
+		OnProcessLine(func(line []byte) error {
+			if string(line) == "bad" {
+				return errors.New("malformed line")
+			}
+			return nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if len(*erroredLines) != 1 {
+		t.Errorf("expected 1 errored line, got %q", *erroredLines)
+	}
+	if lineErr == nil {
+		t.Fatal("expected a *kyro.LineError to be notified")
+	}
+	if lineErr.Error() != "malformed line" || string(lineErr.Line) != "bad" {
+		t.Errorf("unexpected line error: %v for line %q", lineErr, lineErr.Line)
+	}
+	if before := bytes.Join(lineErr.Before, []byte(",")); string(before) != "l2,l3" {
+		t.Errorf("expected lines before to be l2,l3, got %s", before)
+	}
+	if after := bytes.Join(lineErr.After, []byte(",")); string(after) != "l5" {
+		t.Errorf("expected lines after to be l5, got %s", after)
+	}
+}
+
+func TestParallelFileProcessor_WithErrorContext_AtEdges(t *testing.T) {
+	path := writeTempFile(t, "first\nmiddle\nlast\n")
+
+	var mu sync.Mutex
+	contexts := make(map[string]string)
+
+	_, err := kyro.NewParallelFileProcessor(1).
+		WithFilePath(path).
+		WithErrorContext(3, 3).
+		WithErrorNotifier(func(err error, line []byte) {
+			var lineErr *kyro.LineError
+			if errors.As(err, &lineErr) {
+				mu.Lock()
+				defer mu.Unlock()
+				contexts[string(line)] = fmt.Sprintf("%s|%s", bytes.Join(lineErr.Before, []byte(",")), bytes.Join(lineErr.After, []byte(",")))
+			}
+		}).
+		OnProcessLine(func(line []byte) error {
+			return errors.New("failed")
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	expected := map[string]string{"first": "|middle,last", "middle": "first|last", "last": "first,middle|"}
+	if !reflect.DeepEqual(contexts, expected) {
+		t.Errorf("expected contexts %v, got %v", expected, contexts)
+	}
+}
+
+func TestParallelFileProcessor_ErrorReportsLineNumber(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 500; i++ {
+		fmt.Fprintf(&content, "record %d\n", i)
+	}
+	path := writeTempFile(t, content.String())
+
+	var mu sync.Mutex
+	numbers := make(map[string]int)
+
+	_, err := kyro.NewParallelFileProcessor(8).
+		WithFilePath(path).
+		WithErrorNotifier(func(err error, line []byte) {
+			var lineErr *kyro.LineError
+			if errors.As(err, &lineErr) {
+				mu.Lock()
+				defer mu.Unlock()
+				numbers[string(lineErr.Line)] = lineErr.Number
+			}
+		}).
+		OnProcessLine(func(line []byte) error {
+			if string(line) == "record 1" || string(line) == "record 412" {
+				return errors.New("invalid record")
+			}
+			return nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if expected := map[string]int{"record 1": 1, "record 412": 412}; !reflect.DeepEqual(numbers, expected) {
+		t.Errorf("expected line numbers %v, got %v", expected, numbers)
+	}
+}
+
+func TestParallelFileProcessor_WithMaxLineBytes(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	path := writeTempFile(t, "short\n"+long+"\nexactly10!\n")
+
+	var mu sync.Mutex
+	var processed []string
+	var notified error
+
+	erroredLines, err := kyro.NewParallelFileProcessor(2).
+		WithFilePath(path).
+		WithMaxLineBytes(10).
+		WithReadBufferSize(16).
+		WithErrorNotifier(func(err error, line []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			notified = err
+		}).
+		OnProcessLine(func(line []byte) error {
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, string(line))
+			return nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if !errors.Is(notified, kyro.ErrLineTooLong) {
+		t.Errorf("expected ErrLineTooLong to be notified, got: %v", notified)
+	}
+	if len(*erroredLines) != 1 || string((*erroredLines)[0]) != long[:10] {
+		t.Errorf("expected the truncated long line to be errored, got %q", *erroredLines)
+	}
+	if slices.Sort(processed); !slices.Equal(processed, []string{"exactly10!", "short"}) {
+		t.Errorf("expected only the short lines to be processed, got %q", processed)
+	}
+}
+
+func TestParallelFileProcessor_OnTransformLine(t *testing.T) {
+	path := writeTempFile(t, "alpha\nskip\nbeta\nbroken\ngamma\n")
+	outputPath := filepath.Join(t.TempDir(), "output.txt")
+
+	erroredLines, err := kyro.NewParallelFileProcessor(3).
+		WithFilePath(path).
+		WithOutputPath(outputPath).
+		OnTransformLine(func(line []byte) ([]byte, error) {
+			switch string(line) {
+			case "skip":
+				return nil, nil
+			case "broken":
+				return nil, errors.New("broken line")
+			}
+			return bytes.ToUpper(line), nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error for the broken line, got nil")
+	}
+	if len(*erroredLines) != 1 {
+		t.Errorf("expected 1 errored line, got %d", len(*erroredLines))
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(output), "\n"), "\n")
+	slices.Sort(lines)
+	if expected := []string{"ALPHA", "BETA", "GAMMA"}; !slices.Equal(lines, expected) {
+		t.Errorf("expected output lines %v, got %q", expected, output)
+	}
+}
+
+func TestParallelFileProcessor_WithOrderedOutput(t *testing.T) {
+	var content strings.Builder
+	var expected strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&content, "%d\n", i)
+		if i%5 != 0 {
+			fmt.Fprintf(&expected, "line %d\n", i)
+		}
+	}
+	path := writeTempFile(t, content.String())
+	outputPath := filepath.Join(t.TempDir(), "output.txt")
+
+	_, err := kyro.NewParallelFileProcessor(8).
+		WithFilePath(path).
+		WithOutputPath(outputPath).
+		WithOrderedOutput().
+		OnTransformLine(func(line []byte) ([]byte, error) {
+			n, _ := strconv.Atoi(string(line))
+			// Later lines finish first, which scrambles the completion order.
+			time.Sleep(time.Duration(30-n) * time.Millisecond)
+			if n%5 == 0 {
+				return nil, nil
+			}
+			return []byte("line " + string(line)), nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if string(output) != expected.String() {
+		t.Errorf("expected output in input order, got %q", output)
+	}
+}
+
+func TestParallelFileProcessor_OnTransformLine_NoOutputPath(t *testing.T) {
+	path := writeTempFile(t, "line\n")
+
+	_, err := kyro.NewParallelFileProcessor(1).
+		WithFilePath(path).
+		OnTransformLine(func(line []byte) ([]byte, error) { return line, nil }).
+		Process()
+
+	if err == nil {
+		t.Error("expected error without output path, got nil")
+	}
+}
+
+func TestParallelFileProcessor_Resume(t *testing.T) {
+	var content strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&content, "%d\n", i)
+	}
+	lines := strings.SplitAfter(content.String(), "\n")
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint")
+
+	// The first run crashes after reading the first 12 lines.
+	crash := io.MultiReader(strings.NewReader(strings.Join(lines[:12], "")), iotest.ErrReader(errors.New("crash")))
+	_, err := kyro.NewParallelFileProcessor(4).
+		WithReader(crash).
+		WithCheckpoint(checkpointPath).
+		OnProcessLine(func(line []byte) error { return nil }).
+		Process()
+
+	if err == nil {
+		t.Fatal("expected read error from the first run, got nil")
+	}
+
+	checkpoint, err := os.ReadFile(checkpointPath)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if strings.TrimSpace(string(checkpoint)) != "12" {
+		t.Fatalf("expected checkpoint 12, got %q", checkpoint)
+	}
+
+	var mu sync.Mutex
+	var processed []string
+	_, err = kyro.NewParallelFileProcessor(4).
+		WithFilePath(writeTempFile(t, content.String())).
+		WithCheckpoint(checkpointPath).
+		Resume().
+		OnProcessLine(func(line []byte) error {
+			mu.Lock()
+			processed = append(processed, string(line))
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slices.SortFunc(processed, func(a, b string) int {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	})
+	if expected := []string{"13", "14", "15", "16", "17", "18", "19", "20"}; !slices.Equal(processed, expected) {
+		t.Errorf("expected only lines %v to be processed, got %v", expected, processed)
+	}
+
+	checkpoint, err = os.ReadFile(checkpointPath)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if strings.TrimSpace(string(checkpoint)) != "20" {
+		t.Errorf("expected checkpoint 20, got %q", checkpoint)
+	}
+}
+
+func TestParallelFileProcessor_WithCheckpoint_TrailsOutput(t *testing.T) {
+	dir := t.TempDir()
+	outputPath := filepath.Join(dir, "output.txt")
+	checkpointPath := filepath.Join(dir, "checkpoint")
+
+	// The checkpoint is written while "slow" is transformed, and must not cover
+	// lines whose output is not in the file yet.
+	var checkpoint, written int
+	_, err := kyro.NewParallelFileProcessor(1).
+		WithFilePath(writeTempFile(t, "one\ntwo\nslow\nlast\n")).
+		WithOutputPath(outputPath).
+		WithCheckpoint(checkpointPath).
+		OnTransformLine(func(line []byte) ([]byte, error) {
+			switch string(line) {
+			case "slow":
+				time.Sleep(1500 * time.Millisecond)
+			case "last":
+				data, _ := os.ReadFile(checkpointPath)
+				checkpoint, _ = strconv.Atoi(strings.TrimSpace(string(data)))
+				output, _ := os.ReadFile(outputPath)
+				written = bytes.Count(output, []byte("\n"))
+			}
+			return line, nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkpoint > written {
+		t.Errorf("expected the checkpoint to trail the output, got checkpoint %d with %d lines written", checkpoint, written)
+	}
+
+	data, err := os.ReadFile(checkpointPath)
+	if err != nil {
+		t.Fatalf("failed to read checkpoint: %v", err)
+	}
+	if strings.TrimSpace(string(data)) != "4" {
+		t.Errorf("expected final checkpoint 4, got %q", data)
+	}
+}
+
+func TestParallelFileProcessor_ReturnsAllErroredLines(t *testing.T) {
+	var notified atomic.Int32
+	erroredLines, err := kyro.NewParallelFileProcessor(1).
+		WithReader(strings.NewReader("a\nb\nc\nd\ne\n")).
+		OnProcessLine(func(line []byte) error { return errors.New("failure") }).
+		WithErrorNotifier(func(err error, line []byte) { notified.Add(1) }).
+		Process()
+
+	if err == nil || err.Error() != "encountered 5 errors during line processing" {
+		t.Errorf("expected 5 errors, got: %v", err)
+	}
+	if erroredLines == nil || len(*erroredLines) != 5 {
+		t.Fatalf("expected 5 errored lines, got %v", erroredLines)
+	}
+	if notified.Load() != 5 {
+		t.Errorf("expected 5 notifications, got %d", notified.Load())
+	}
+}
+
+func TestParallelFileProcessor_Resume_NoCheckpointFile(t *testing.T) {
+	path := writeTempFile(t, "one\ntwo\nthree\n")
+
+	var processed atomic.Int32
+	_, err := kyro.NewParallelFileProcessor(2).
+		WithFilePath(path).
+		WithCheckpoint(filepath.Join(t.TempDir(), "checkpoint")).
+		Resume().
+		OnProcessLine(func(line []byte) error {
+			processed.Add(1)
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if processed.Load() != 3 {
+		t.Errorf("expected all 3 lines to be processed, got %d", processed.Load())
+	}
+}
+
+func TestParallelFileProcessor_WithFilePaths(t *testing.T) {
+	first := writeTempFile(t, "a1\na2\nbad\n")
+	second := writeTempFile(t, "b1\nbad\nb3\nb4\n")
+
+	var mu sync.Mutex
+	var processed []string
+	var lineErrors []*kyro.LineError
+	var progress int
+
+	erroredLines, err := kyro.NewParallelFileProcessor(3).
+		WithFilePaths([]string{first, second}).
+		WithProgressNotifier(1, func(curr int, duration time.Duration, itemsPerSecond float64) {
+			mu.Lock()
+			progress = max(progress, curr)
+			mu.Unlock()
+		}).
+		WithErrorNotifier(func(err error, line []byte) {
+			var lineErr *kyro.LineError
+			if errors.As(err, &lineErr) {
+				mu.Lock()
+				lineErrors = append(lineErrors, lineErr)
+				mu.Unlock()
+			}
+		}).
+		OnProcessLine(func(line []byte) error {
+			if string(line) == "bad" {
+				return errors.New("bad line")
+			}
+			mu.Lock()
+			processed = append(processed, string(line))
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if len(*erroredLines) != 2 {
+		t.Errorf("expected 2 errored lines, got %d", len(*erroredLines))
+	}
+
+	slices.Sort(processed)
+	if expected := []string{"a1", "a2", "b1", "b3", "b4"}; !slices.Equal(processed, expected) {
+		t.Errorf("expected lines %v to be processed, got %v", expected, processed)
+	}
+	if progress != 7 {
+		t.Errorf("expected progress to count 7 lines across both files, got %d", progress)
+	}
+
+	got := make(map[string]int)
+	for _, lineErr := range lineErrors {
+		got[lineErr.Path] = lineErr.Number
+	}
+	if expected := map[string]int{first: 3, second: 2}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected failed lines at %v, got %v", expected, got)
+	}
+}
+
+func TestParallelFileProcessor_WithByteProgressNotifier(t *testing.T) {
+	var content strings.Builder
+	for i := range 2000 {
+		content.WriteString(strings.Repeat("x", i%97))
+		content.WriteString("\n")
+	}
+	path := writeTempFile(t, content.String())
+
+	var lastRead, lastTotal int64
+	var lastPct float64
+	var calls int
+
+	_, err := kyro.NewParallelFileProcessor(4).
+		WithFilePath(path).
+		WithByteProgressNotifier(func(bytesRead, totalBytes int64, pct float64) {
+			if pct < lastPct {
+				t.Errorf("expected percentage to grow, got %v after %v", pct, lastPct)
+			}
+			lastRead, lastTotal, lastPct = bytesRead, totalBytes, pct
+			calls++
+		}).
+		OnProcessLine(func(line []byte) error { return nil }).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls < 2 {
+		t.Errorf("expected several progress notifications, got %d", calls)
+	}
+	if lastTotal != int64(content.Len()) || lastRead != lastTotal {
+		t.Errorf("expected %d of %d bytes read, got %d of %d", content.Len(), content.Len(), lastRead, lastTotal)
+	}
+	if lastPct < 99.999 {
+		t.Errorf("expected final percentage of 100, got %v", lastPct)
+	}
+}
+
+func TestParallelFileProcessor_WithBlockSize(t *testing.T) {
+	content := make([]byte, 100)
+	for i := range content {
+		content[i] = byte(i)
+	}
+	path := writeTempFile(t, string(content))
+
+	var mu sync.Mutex
+	var blocks [][]byte
+	_, err := kyro.NewParallelFileProcessor(3).
+		WithFilePath(path).
+		WithBlockSize(10).
+		OnProcessLine(func(block []byte) error {
+			mu.Lock()
+			blocks = append(blocks, block)
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(blocks) != 10 {
+		t.Fatalf("expected 10 blocks, got %d", len(blocks))
+	}
+
+	slices.SortFunc(blocks, bytes.Compare)
+	for i, block := range blocks {
+		if !bytes.Equal(block, content[i*10:(i+1)*10]) {
+			t.Errorf("expected block %d to be %v, got %v", i, content[i*10:(i+1)*10], block)
+		}
+	}
+}
+
+func TestParallelFileProcessor_WithBlockSize_ShortBlock(t *testing.T) {
+	path := writeTempFile(t, "0123456789abcde")
+
+	var mu sync.Mutex
+	var blocks []string
+	processor := kyro.NewParallelFileProcessor(1).
+		WithFilePath(path).
+		WithBlockSize(10).
+		OnProcessLine(func(block []byte) error {
+			mu.Lock()
+			blocks = append(blocks, string(block))
+			mu.Unlock()
+			return nil
+		})
+
+	if _, err := processor.Process(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"0123456789", "abcde"}; !slices.Equal(blocks, expected) {
+		t.Errorf("expected blocks %v, got %v", expected, blocks)
+	}
+
+	blocks = nil
+	var reported error
+	erroredLines, err := processor.
+		WithRejectShortBlock().
+		WithErrorNotifier(func(err error, line []byte) { reported = err }).
+		Process()
+
+	if err == nil {
+		t.Error("expected error for the short block, got nil")
+	}
+	if !errors.Is(reported, kyro.ErrShortBlock) {
+		t.Errorf("expected ErrShortBlock, got %v", reported)
+	}
+	if len(*erroredLines) != 1 || string((*erroredLines)[0]) != "abcde" {
+		t.Errorf("expected the short block to be errored, got %q", *erroredLines)
+	}
+	if expected := []string{"0123456789"}; !slices.Equal(blocks, expected) {
+		t.Errorf("expected blocks %v, got %v", expected, blocks)
+	}
+}
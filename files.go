@@ -1,6 +1,11 @@
 package kyro
 
-import "os"
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+)
 
 func SafeRemoveFile(path string) error {
 	if _, err := os.Stat(path); err == nil {
@@ -12,3 +17,53 @@ func SafeRemoveFile(path string) error {
 
 	return nil
 }
+
+// FileLineDiff compares the lines of the files at a and b as sets, e.g. the IDs exported by
+// two runs, and returns the distinct lines found only in a and only in b, each sorted. The
+// lines of a are held in memory, while b is streamed and only its lines missing from a are
+// kept. Lines are limited to bufio.MaxScanTokenSize bytes.
+func FileLineDiff(a, b string) (onlyInA, onlyInB []string, err error) {
+	linesA := NewSimpleSet[string](0)
+	if err := scanFileLines(a, linesA.Add); err != nil {
+		return nil, nil, err
+	}
+
+	common := NewSimpleSet[string](0)
+	linesB := NewSimpleSet[string](0)
+	err = scanFileLines(b, func(line string) {
+		if linesA.Contains(line) {
+			common.Add(line)
+		} else {
+			linesB.Add(line)
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	onlyInA = linesA.Difference(common).AsSlice()
+	onlyInB = linesB.AsSlice()
+	slices.Sort(onlyInA)
+	slices.Sort(onlyInB)
+
+	return onlyInA, onlyInB, nil
+}
+
+// scanFileLines calls fn with every line of the file at path.
+func scanFileLines(path string, fn func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fn(scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
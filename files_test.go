@@ -0,0 +1,46 @@
+package kyro_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestFileLineDiff(t *testing.T) {
+	a := writeTempFile(t, "id-1\nid-2\nid-3\nid-2\nid-5\n")
+	b := writeTempFile(t, "id-3\nid-4\nid-1\nid-6\nid-4\n")
+
+	onlyInA, onlyInB, err := kyro.FileLineDiff(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{"id-2", "id-5"}; !slices.Equal(onlyInA, expected) {
+		t.Errorf("expected only in a %v, got %v", expected, onlyInA)
+	}
+	if expected := []string{"id-4", "id-6"}; !slices.Equal(onlyInB, expected) {
+		t.Errorf("expected only in b %v, got %v", expected, onlyInB)
+	}
+}
+
+func TestFileLineDiff_Identical(t *testing.T) {
+	a := writeTempFile(t, "x\ny\n")
+	b := writeTempFile(t, "y\nx\n")
+
+	onlyInA, onlyInB, err := kyro.FileLineDiff(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(onlyInA) != 0 || len(onlyInB) != 0 {
+		t.Errorf("expected no differences, got %v and %v", onlyInA, onlyInB)
+	}
+}
+
+func TestFileLineDiff_MissingFile(t *testing.T) {
+	a := writeTempFile(t, "x\n")
+
+	if _, _, err := kyro.FileLineDiff(a, a+".missing"); err == nil {
+		t.Error("expected error for a missing file, got nil")
+	}
+}
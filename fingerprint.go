@@ -0,0 +1,23 @@
+package kyro
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+)
+
+// Fingerprint returns a stable 64-bit hash of v, e.g. to derive a deduplication, sharding or
+// cache key from a value that is not comparable. The hash is the FNV-64a hash of the JSON
+// encoding of v, so values with the same encoding share a fingerprint: map keys are sorted,
+// unexported fields are ignored, and a nil slice hashes like a nil map. An error is returned
+// if v cannot be encoded as JSON, e.g. because it holds a channel or a function.
+func Fingerprint(v any) (uint64, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode value for fingerprint: %w", err)
+	}
+
+	hash := fnv.New64a()
+	hash.Write(encoded)
+	return hash.Sum64(), nil
+}
@@ -0,0 +1,48 @@
+package kyro_test
+
+import (
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestFingerprint(t *testing.T) {
+	type record struct {
+		ID   int
+		Tags []string
+		Meta map[string]int
+	}
+
+	fingerprint := func(v any) uint64 {
+		t.Helper()
+
+		hash, err := kyro.Fingerprint(v)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return hash
+	}
+
+	a := record{ID: 1, Tags: []string{"x", "y"}, Meta: map[string]int{"a": 1, "b": 2, "c": 3}}
+	b := record{ID: 1, Tags: []string{"x", "y"}, Meta: map[string]int{"c": 3, "b": 2, "a": 1}}
+
+	if fingerprint(a) != fingerprint(b) {
+		t.Error("expected identical values to have the same fingerprint")
+	}
+
+	for _, different := range []record{
+		{ID: 2, Tags: a.Tags, Meta: a.Meta},
+		{ID: 1, Tags: []string{"y", "x"}, Meta: a.Meta},
+		{ID: 1, Tags: a.Tags, Meta: map[string]int{"a": 1}},
+	} {
+		if fingerprint(a) == fingerprint(different) {
+			t.Errorf("expected %+v to have a different fingerprint than %+v", different, a)
+		}
+	}
+}
+
+func TestFingerprint_UnsupportedValue(t *testing.T) {
+	if _, err := kyro.Fingerprint(make(chan int)); err == nil {
+		t.Error("expected error for a channel, got nil")
+	}
+}
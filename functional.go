@@ -1,5 +1,9 @@
 package kyro
 
+import "sync"
+
+// Map returns a new slice holding the result of fn for every element of ts.
+// An empty or nil input yields an empty, non-nil slice.
 func Map[T, V any](ts []T, fn func(val T, index int) V) []V {
 	result := make([]V, len(ts))
 	for i, t := range ts {
@@ -8,6 +12,8 @@ func Map[T, V any](ts []T, fn func(val T, index int) V) []V {
 	return result
 }
 
+// FindFirst returns a pointer to the first element of slice matching predicate.
+// It returns nil if no element matches, which includes an empty or nil input.
 func FindFirst[T any](slice []T, predicate func(T) bool) *T {
 	for _, item := range slice {
 		if predicate(item) {
@@ -17,6 +23,8 @@ func FindFirst[T any](slice []T, predicate func(T) bool) *T {
 	return nil
 }
 
+// Filter returns a new slice holding the elements of slice matching predicate.
+// An empty or nil input yields an empty, non-nil slice.
 func Filter[T any](slice []T, predicate func(T) bool) []T {
 	result := make([]T, 0, len(slice))
 	for _, item := range slice {
@@ -26,3 +34,104 @@ func Filter[T any](slice []T, predicate func(T) bool) []T {
 	}
 	return result
 }
+
+// IsEmpty reports whether slice has no elements. A nil slice is considered empty.
+func IsEmpty[T any](slice []T) bool {
+	return len(slice) == 0
+}
+
+// Associate returns a map holding the elements of slice keyed by keyFn. If several
+// elements share a key, the last one wins. An empty or nil input yields an empty map.
+func Associate[T any, K comparable](slice []T, keyFn func(T) K) map[K]T {
+	result := make(map[K]T, len(slice))
+	for _, item := range slice {
+		result[keyFn(item)] = item
+	}
+	return result
+}
+
+// MapValues returns the values of m as a slice. The order of the values is not specified.
+// An empty or nil input yields an empty, non-nil slice.
+func MapValues[K comparable, V any](m map[K]V) []V {
+	result := make([]V, 0, len(m))
+	for _, value := range m {
+		result = append(result, value)
+	}
+	return result
+}
+
+// MergeMaps returns a new map holding the entries of all maps. If several maps hold the
+// same key, the value of the last of them wins. The input maps are not modified.
+func MergeMaps[K comparable, V any](maps ...map[K]V) map[K]V {
+	return MergeMapsFunc(func(key K, existing, incoming V) V { return incoming }, maps...)
+}
+
+// MergeMapsFunc works like MergeMaps, but resolves a key held by several maps with resolve,
+// which receives the key, the value merged so far and the value of the next map holding it.
+func MergeMapsFunc[K comparable, V any](resolve func(key K, existing, incoming V) V, maps ...map[K]V) map[K]V {
+	size := 0
+	for _, m := range maps {
+		size += len(m)
+	}
+
+	result := make(map[K]V, size)
+	for _, m := range maps {
+		for key, value := range m {
+			if existing, ok := result[key]; ok {
+				value = resolve(key, existing, value)
+			}
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// Memoize returns a function that calls fn once per distinct key and returns the cached
+// value for every later call with the same key. The returned function is safe for
+// concurrent use; concurrent calls with the same key wait for the first one to complete.
+// The cache is never evicted, so fn should be a pure function over a bounded set of keys.
+func Memoize[K comparable, V any](fn func(K) V) func(K) V {
+	memoized := MemoizeErr(func(key K) (V, error) {
+		return fn(key), nil
+	})
+
+	return func(key K) V {
+		value, _ := memoized(key)
+		return value
+	}
+}
+
+// MemoizeErr works like Memoize for a fallible fn. Only successful results are cached:
+// an error is returned to all callers waiting for that call, and the next call with the
+// same key invokes fn again.
+func MemoizeErr[K comparable, V any](fn func(K) (V, error)) func(K) (V, error) {
+	type entry struct {
+		once  sync.Once
+		value V
+		err   error
+	}
+
+	var mu sync.Mutex
+	entries := make(map[K]*entry)
+
+	return func(key K) (V, error) {
+		mu.Lock()
+		e, ok := entries[key]
+		if !ok {
+			e = &entry{}
+			entries[key] = e
+		}
+		mu.Unlock()
+
+		e.once.Do(func() {
+			e.value, e.err = fn(key)
+			if e.err != nil {
+				mu.Lock()
+				delete(entries, key)
+				mu.Unlock()
+			}
+		})
+
+		return e.value, e.err
+	}
+}
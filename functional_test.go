@@ -0,0 +1,188 @@
+package kyro_test
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestFunctional_EmptyInputs(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []int
+	}{
+		{name: "nil slice", input: nil},
+		{name: "empty slice", input: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapped := kyro.Map(tt.input, func(val int, index int) int { return val * 2 })
+			if mapped == nil || len(mapped) != 0 {
+				t.Errorf("Map: expected empty non-nil slice, got %#v", mapped)
+			}
+
+			filtered := kyro.Filter(tt.input, func(val int) bool { return true })
+			if filtered == nil || len(filtered) != 0 {
+				t.Errorf("Filter: expected empty non-nil slice, got %#v", filtered)
+			}
+
+			if found := kyro.FindFirst(tt.input, func(val int) bool { return true }); found != nil {
+				t.Errorf("FindFirst: expected nil, got %v", *found)
+			}
+
+			if !kyro.IsEmpty(tt.input) {
+				t.Error("IsEmpty: expected true")
+			}
+		})
+	}
+}
+
+func TestFunctional_SingleElement(t *testing.T) {
+	input := []int{7}
+
+	mapped := kyro.Map(input, func(val int, index int) int { return val + index })
+	if len(mapped) != 1 || mapped[0] != 7 {
+		t.Errorf("Map: expected [7], got %v", mapped)
+	}
+
+	filtered := kyro.Filter(input, func(val int) bool { return val > 5 })
+	if len(filtered) != 1 || filtered[0] != 7 {
+		t.Errorf("Filter: expected [7], got %v", filtered)
+	}
+
+	found := kyro.FindFirst(input, func(val int) bool { return val == 7 })
+	if found == nil || *found != 7 {
+		t.Errorf("FindFirst: expected 7, got %v", found)
+	}
+
+	if kyro.IsEmpty(input) {
+		t.Error("IsEmpty: expected false")
+	}
+}
+
+func TestAssociate_LastWins(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	users := []user{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}, {ID: 1, Name: "c"}}
+	byID := kyro.Associate(users, func(u user) int { return u.ID })
+
+	if len(byID) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(byID))
+	}
+	if byID[1].Name != "c" {
+		t.Errorf("expected last element to win for key 1, got %v", byID[1])
+	}
+	if byID[2].Name != "b" {
+		t.Errorf("expected 'b' for key 2, got %v", byID[2])
+	}
+}
+
+func TestMapValues(t *testing.T) {
+	values := kyro.MapValues(map[string]int{"a": 1, "b": 2})
+	slices.Sort(values)
+
+	if !slices.Equal(values, []int{1, 2}) {
+		t.Errorf("expected [1 2], got %v", values)
+	}
+
+	if empty := kyro.MapValues[string, int](nil); empty == nil || len(empty) != 0 {
+		t.Errorf("expected empty non-nil slice, got %#v", empty)
+	}
+}
+
+func TestMergeMaps_LastWins(t *testing.T) {
+	base := map[string]int{"a": 1, "b": 2}
+	override := map[string]int{"b": 20, "c": 30}
+
+	merged := kyro.MergeMaps(base, nil, override)
+
+	if expected := map[string]int{"a": 1, "b": 20, "c": 30}; !maps.Equal(merged, expected) {
+		t.Errorf("expected %v, got %v", expected, merged)
+	}
+	if base["b"] != 2 || len(base) != 2 || len(override) != 2 {
+		t.Errorf("expected inputs to be unchanged, got %v and %v", base, override)
+	}
+	if merged := kyro.MergeMaps[string, int](); merged == nil || len(merged) != 0 {
+		t.Errorf("expected empty non-nil map, got %#v", merged)
+	}
+}
+
+func TestMergeMapsFunc_CustomResolver(t *testing.T) {
+	counts := []map[string]int{{"a": 1, "b": 2}, {"b": 3}, {"b": 4, "c": 5}}
+
+	merged := kyro.MergeMapsFunc(func(key string, existing, incoming int) int {
+		return existing + incoming
+	}, counts...)
+
+	if expected := map[string]int{"a": 1, "b": 9, "c": 5}; !maps.Equal(merged, expected) {
+		t.Errorf("expected %v, got %v", expected, merged)
+	}
+}
+
+func TestMemoize_RunsOncePerKeyConcurrently(t *testing.T) {
+	var calls sync.Map
+	square := kyro.Memoize(func(n int) int {
+		count, _ := calls.LoadOrStore(n, new(atomic.Int32))
+		count.(*atomic.Int32).Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return n * n
+	})
+
+	var wg sync.WaitGroup
+	for i := range 100 {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			if got := square(n); got != n*n {
+				t.Errorf("expected %d, got %d", n*n, got)
+			}
+		}(i % 5)
+	}
+	wg.Wait()
+
+	for key := range 5 {
+		count, ok := calls.Load(key)
+		if !ok || count.(*atomic.Int32).Load() != 1 {
+			t.Errorf("expected fn to run once for key %d", key)
+		}
+	}
+}
+
+func TestMemoizeErr_DoesNotCacheErrors(t *testing.T) {
+	calls := 0
+	lookup := kyro.MemoizeErr(func(key string) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errors.New("transient failure")
+		}
+		return len(key), nil
+	})
+
+	if _, err := lookup("abc"); err == nil {
+		t.Error("expected error on first call, got nil")
+	}
+
+	for range 2 {
+		value, err := lookup("abc")
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if value != 3 {
+			t.Errorf("expected 3, got %d", value)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
@@ -0,0 +1,44 @@
+package kyro
+
+import "sync"
+
+// InFlight bounds the number of operations in flight at once, e.g. asynchronous requests
+// dispatched by the workers of a ParallelQueue. A nil *InFlight does not limit anything.
+type InFlight struct {
+	tokens chan struct{}
+}
+
+// NewInFlight creates a new InFlight allowing up to n operations at once.
+// A non-positive n is treated as 1.
+func NewInFlight(n int) *InFlight {
+	return &InFlight{tokens: make(chan struct{}, max(n, 1))}
+}
+
+// Acquire blocks until another operation may start and returns the function that must be
+// called once the operation is done. The returned function is safe to call multiple times.
+func (f *InFlight) Acquire() (release func()) {
+	if f == nil {
+		return func() {}
+	}
+
+	f.tokens <- struct{}{}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-f.tokens })
+	}
+}
+
+// Wait blocks until all operations in flight have been released.
+func (f *InFlight) Wait() {
+	if f == nil {
+		return
+	}
+
+	for range cap(f.tokens) {
+		f.tokens <- struct{}{}
+	}
+	for range cap(f.tokens) {
+		<-f.tokens
+	}
+}
@@ -0,0 +1,44 @@
+package kyro_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestInFlight_AcquireBlocksAtLimit(t *testing.T) {
+	inFlight := kyro.NewInFlight(1)
+
+	release := inFlight.Acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		defer close(acquired)
+		inFlight.Acquire()()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("expected Acquire to block while the limit is reached")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	release()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("expected Acquire to proceed after release")
+	}
+
+	inFlight.Wait()
+}
+
+func TestInFlight_Nil(t *testing.T) {
+	var inFlight *kyro.InFlight
+
+	inFlight.Acquire()()
+	inFlight.Wait()
+}
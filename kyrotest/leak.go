@@ -0,0 +1,35 @@
+// Package kyrotest provides helpers for testing code built on kyro.
+package kyrotest
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// leakSettleTimeout is how long AssertNoGoroutineLeak waits for goroutines to exit.
+const leakSettleTimeout = time.Second
+
+// AssertNoGoroutineLeak runs fn and fails the test if more goroutines are running afterwards
+// than before. Goroutines commonly exit shortly after the call that started them returns, so
+// the count is polled until it settles or a second has passed. It is based on the number of
+// goroutines of the whole process, so it must not be used in tests running in parallel.
+func AssertNoGoroutineLeak(t testing.TB, fn func()) {
+	t.Helper()
+
+	before := runtime.NumGoroutine()
+	fn()
+
+	deadline := time.Now().Add(leakSettleTimeout)
+	after := runtime.NumGoroutine()
+	for after > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		after = runtime.NumGoroutine()
+	}
+
+	if after > before {
+		stacks := make([]byte, 1<<16)
+		stacks = stacks[:runtime.Stack(stacks, true)]
+		t.Errorf("leaked %d goroutines, %d running before and %d after:\n%s", after-before, before, after, stacks)
+	}
+}
@@ -2,6 +2,8 @@ package kyro
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"golang.org/x/time/rate"
 )
@@ -18,8 +20,191 @@ func NewRateLimiter(r int, b int) *RateLimiter {
 	return &RateLimiter{limiter: rate.NewLimiter(rate.Limit(r), b)}
 }
 
+// NewRateLimiterPer creates a new RateLimiter allowing n events per period, e.g. 500 per
+// minute, with the given burst size. If n is zero or less, no events are allowed beyond the
+// burst, as with a rate of zero. Otherwise, if period is zero or less, events are not limited.
+func NewRateLimiterPer(n int, period time.Duration, b int) *RateLimiter {
+	switch {
+	case n <= 0:
+		return &RateLimiter{limiter: rate.NewLimiter(0, b)}
+	case period <= 0:
+		return &RateLimiter{limiter: rate.NewLimiter(rate.Inf, b)}
+	}
+
+	// The rate is computed in floating point, as dividing period by n would truncate
+	// it, down to an interval of zero, i.e. no limit at all, for n above the nanoseconds.
+	return &RateLimiter{limiter: rate.NewLimiter(rate.Limit(float64(n)/period.Seconds()), b)}
+}
+
 // Wait waits for the rate limiter to allow an event. It blocks until the limiter allows the event
 // or the context is cancelled. This function uses context.Background() for simplicity.
 func (rl *RateLimiter) Wait() error {
-	return rl.limiter.Wait(context.Background())
+	return rl.WaitContext(context.Background())
+}
+
+// WaitContext waits for the rate limiter to allow an event like Wait, but returns ctx.Err()
+// as soon as ctx is cancelled. If ctx has a deadline that passes before the event would be
+// allowed, it fails right away without waiting for the deadline.
+func (rl *RateLimiter) WaitContext(ctx context.Context) error {
+	return rl.limiter.Wait(ctx)
+}
+
+// WaitN waits for the rate limiter to allow n events at once, e.g. for a request that
+// counts as several. It fails right away if n exceeds the burst size. This function uses
+// context.Background() for simplicity.
+func (rl *RateLimiter) WaitN(n int) error {
+	return rl.limiter.WaitN(context.Background(), n)
+}
+
+// Allow reports whether an event may happen now without waiting, and if so counts it.
+func (rl *RateLimiter) Allow() bool {
+	return rl.limiter.Allow()
+}
+
+// AllowN reports whether n events may happen now without waiting, and if so counts them.
+func (rl *RateLimiter) AllowN(n int) bool {
+	return rl.limiter.AllowN(time.Now(), n)
+}
+
+// ReserveDelay returns how long an event would have to wait for the rate limiter if it
+// happened now, e.g. to display when the next request is due. It does not consume any
+// allowance, so the next event is not delayed by asking. If the limiter can never allow an
+// event, e.g. with a burst size of 0, it returns rate.InfDuration.
+func (rl *RateLimiter) ReserveDelay() time.Duration {
+	now := time.Now()
+	reservation := rl.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return rate.InfDuration
+	}
+
+	delay := reservation.DelayFrom(now)
+	reservation.CancelAt(now)
+	return delay
+}
+
+// SetLimit changes the rate to r events per second, e.g. following the rate limit headers of
+// an upstream service. The tokens accumulated so far are kept. It is safe to call while other
+// goroutines are waiting on the limiter.
+func (rl *RateLimiter) SetLimit(r int) {
+	rl.limiter.SetLimit(rate.Limit(r))
+}
+
+// SetBurst changes the burst size to b. The tokens accumulated so far are kept, up to b.
+// It is safe to call while other goroutines are waiting on the limiter.
+func (rl *RateLimiter) SetBurst(b int) {
+	rl.limiter.SetBurst(b)
+}
+
+// CompositeRateLimiter combines several RateLimiters, allowing an event only once all of
+// them allow it, e.g. to honor both "10 per second" and "500 per minute". The limiters may
+// be shared with other users, which then count against the composite as well.
+type CompositeRateLimiter struct {
+	limiters []*RateLimiter
+}
+
+// NewCompositeRateLimiter creates a CompositeRateLimiter over the given limiters.
+func NewCompositeRateLimiter(limiters ...*RateLimiter) *CompositeRateLimiter {
+	return &CompositeRateLimiter{limiters: limiters}
+}
+
+// Wait waits for each limiter to allow the event in turn. As the limiters are waited on one
+// after the other, an event held back by one limiter may already have used up the allowance
+// of the limiters before it. This function uses context.Background() for simplicity.
+func (c *CompositeRateLimiter) Wait() error {
+	for _, rl := range c.limiters {
+		if err := rl.Wait(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Allow reports whether all limiters allow an event right now and, if so, counts the event
+// against all of them. If any limiter does not allow it, none of them is charged.
+func (c *CompositeRateLimiter) Allow() bool {
+	now := time.Now()
+	reservations := make([]*rate.Reservation, 0, len(c.limiters))
+
+	for _, rl := range c.limiters {
+		reservation := rl.limiter.ReserveN(now, 1)
+		if !reservation.OK() || reservation.DelayFrom(now) > 0 {
+			reservation.CancelAt(now)
+			for _, reserved := range reservations {
+				reserved.CancelAt(now)
+			}
+			return false
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return true
+}
+
+// KeyedRateLimiter holds a separate RateLimiter per key, e.g. per host when crawling many
+// hosts, all sharing the same rate and burst. The limiter of a key is created on its first
+// use and kept for the lifetime of the KeyedRateLimiter.
+// It is safe for concurrent use by multiple goroutines.
+type KeyedRateLimiter[K comparable] struct {
+	r        int
+	b        int
+	mu       sync.Mutex
+	limiters map[K]*RateLimiter
+}
+
+// NewKeyedRateLimiter creates a new KeyedRateLimiter whose limiters allow r events per
+// second with a burst size of b, see NewRateLimiter.
+func NewKeyedRateLimiter[K comparable](r int, b int) *KeyedRateLimiter[K] {
+	return &KeyedRateLimiter[K]{r: r, b: b, limiters: make(map[K]*RateLimiter)}
+}
+
+// Limiter returns the RateLimiter of key, creating it if it does not exist yet.
+func (k *KeyedRateLimiter[K]) Limiter(key K) *RateLimiter {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	rl, exists := k.limiters[key]
+	if !exists {
+		rl = NewRateLimiter(k.r, k.b)
+		k.limiters[key] = rl
+	}
+	return rl
+}
+
+// Wait waits for the limiter of key to allow an event. Waiting on one key never holds back
+// another. This function uses context.Background() for simplicity.
+func (k *KeyedRateLimiter[K]) Wait(key K) error {
+	return k.Limiter(key).Wait()
+}
+
+// StaggeredStart returns a randomized start delay for each of the given number of workers,
+// so that workers sharing a rate limiter do not all hit it at the same moment. The window
+// is split into one slot per worker and every delay is picked at random within its own slot,
+// which keeps the delays distinct and within [0, window). If the window is shorter than
+// workers nanoseconds, the delays cannot be distinct; they are then spread evenly over
+// [0, window) without randomization, and all zero for a non-positive window. Worker i should
+// sleep for the i-th delay before issuing its first request. The delays are reproducible
+// with SetRandSource.
+func StaggeredStart(workers int, window time.Duration) []time.Duration {
+	if workers <= 0 {
+		return []time.Duration{}
+	}
+
+	delays := make([]time.Duration, workers)
+	if window <= 0 {
+		return delays
+	}
+
+	slot := window / time.Duration(workers)
+	if slot <= 0 {
+		for i := range delays {
+			delays[i] = window * time.Duration(i) / time.Duration(workers)
+		}
+		return delays
+	}
+
+	for i := range delays {
+		delays[i] = time.Duration(i)*slot + time.Duration(randInt64N(int64(slot)))
+	}
+
+	return delays
 }
@@ -1,6 +1,10 @@
 package kyro_test
 
 import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"slices"
 	"testing"
 	"time"
 
@@ -42,3 +46,301 @@ func TestRateLimiter_Wait(t *testing.T) {
 		t.Errorf("Third Wait did not block long enough. Expected at least %v, got %v", expectedMinDelay, duration)
 	}
 }
+
+func TestStaggeredStart_DistinctAndWithinWindow(t *testing.T) {
+	window := 500 * time.Millisecond
+	delays := kyro.StaggeredStart(10, window)
+
+	if len(delays) != 10 {
+		t.Fatalf("expected 10 delays, got %d", len(delays))
+	}
+
+	seen := make(map[time.Duration]bool)
+	for i, delay := range delays {
+		if delay < 0 || delay >= window {
+			t.Errorf("delay %d out of window: %v", i, delay)
+		}
+		if seen[delay] {
+			t.Errorf("delay %d is not distinct: %v", i, delay)
+		}
+		seen[delay] = true
+	}
+}
+
+func TestStaggeredStart_NoWorkers(t *testing.T) {
+	if delays := kyro.StaggeredStart(0, time.Second); len(delays) != 0 {
+		t.Errorf("expected no delays, got %v", delays)
+	}
+}
+
+func TestStaggeredStart_WindowShorterThanWorkers(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		want   []time.Duration
+	}{
+		{name: "short window", window: 4, want: []time.Duration{0, 0, 0, 1, 1, 2, 2, 2, 3, 3}},
+		{name: "zero window", window: 0, want: make([]time.Duration, 10)},
+		{name: "negative window", window: -time.Second, want: make([]time.Duration, 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if delays := kyro.StaggeredStart(10, tt.window); !slices.Equal(delays, tt.want) {
+				t.Errorf("expected delays %v, got %v", tt.want, delays)
+			}
+		})
+	}
+}
+
+func TestStaggeredStart_SetRandSource(t *testing.T) {
+	defer kyro.SetRandSource(nil)
+
+	kyro.SetRandSource(rand.NewPCG(1, 2))
+	first := kyro.StaggeredStart(10, time.Second)
+
+	kyro.SetRandSource(rand.NewPCG(1, 2))
+	second := kyro.StaggeredStart(10, time.Second)
+
+	if !slices.Equal(first, second) {
+		t.Errorf("expected identical delays for the same seed, got %v and %v", first, second)
+	}
+
+	kyro.SetRandSource(rand.NewPCG(3, 4))
+	if other := kyro.StaggeredStart(10, time.Second); slices.Equal(first, other) {
+		t.Errorf("expected different delays for another seed, got %v twice", other)
+	}
+}
+
+func TestCompositeRateLimiter_TighterLimiterGoverns(t *testing.T) {
+	perSecond := kyro.NewRateLimiter(1000, 1)
+	perWindow := kyro.NewRateLimiterPer(1, 100*time.Millisecond, 1)
+	composite := kyro.NewCompositeRateLimiter(perSecond, perWindow)
+
+	start := time.Now()
+	for range 4 {
+		if err := composite.Wait(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// The first event passes right away, the other three wait 100ms each for the
+	// tighter limiter, while the looser one alone would pass them in about 3ms.
+	if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+		t.Errorf("expected the tighter limiter to govern, took only %v", elapsed)
+	}
+}
+
+func TestNewRateLimiterPer_NonPositiveN(t *testing.T) {
+	for _, n := range []int{0, -1} {
+		rl := kyro.NewRateLimiterPer(n, time.Second, 2)
+
+		for i := range 2 {
+			if !rl.Allow() {
+				t.Errorf("n=%d: expected event %d to be allowed by the burst", n, i)
+			}
+		}
+		if rl.Allow() {
+			t.Errorf("n=%d: expected no events beyond the burst", n)
+		}
+	}
+}
+
+func TestNewRateLimiterPer_LargeN(t *testing.T) {
+	// More events than nanoseconds in the period still make a finite rate, which never
+	// allows more events at once than the burst.
+	rl := kyro.NewRateLimiterPer(2_000_000_000, time.Second, 1)
+	if rl.AllowN(2) {
+		t.Error("expected events beyond the burst to be denied")
+	}
+
+	// Without a period, events are not limited at all.
+	unlimited := kyro.NewRateLimiterPer(1, 0, 1)
+	if !unlimited.AllowN(2) {
+		t.Error("expected events beyond the burst to be allowed without a period")
+	}
+}
+
+func TestCompositeRateLimiter_Allow(t *testing.T) {
+	loose := kyro.NewRateLimiter(1, 5)
+	tight := kyro.NewRateLimiterPer(1, time.Hour, 1)
+	composite := kyro.NewCompositeRateLimiter(loose, tight)
+
+	if !composite.Allow() {
+		t.Fatal("expected the first event to be allowed")
+	}
+	for range 3 {
+		if composite.Allow() {
+			t.Fatal("expected further events to be denied by the tight limiter")
+		}
+	}
+
+	// Denied events must not have used up the burst of the loose limiter.
+	looseOnly := kyro.NewCompositeRateLimiter(loose)
+	for i := range 4 {
+		if !looseOnly.Allow() {
+			t.Fatalf("expected event %d to be allowed by the loose limiter", i)
+		}
+	}
+}
+
+func TestRateLimiter_Allow(t *testing.T) {
+	rl := kyro.NewRateLimiterPer(1, time.Hour, 3)
+
+	for i := range 3 {
+		if !rl.Allow() {
+			t.Fatalf("expected event %d within the burst to be allowed", i)
+		}
+	}
+	if rl.Allow() {
+		t.Error("expected event to be denied once the burst is exhausted")
+	}
+}
+
+func TestRateLimiter_AllowN(t *testing.T) {
+	rl := kyro.NewRateLimiterPer(1, time.Hour, 5)
+
+	if !rl.AllowN(3) {
+		t.Fatal("expected 3 events within the burst to be allowed")
+	}
+	if rl.AllowN(3) {
+		t.Error("expected 3 more events to be denied with only 2 left")
+	}
+	if !rl.AllowN(2) {
+		t.Error("expected the remaining 2 events to be allowed")
+	}
+}
+
+func TestRateLimiter_WaitN(t *testing.T) {
+	rl := kyro.NewRateLimiter(20, 4)
+
+	// The burst is consumed right away, after which 4 events take 4/20s = 200ms.
+	if err := rl.WaitN(4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	if err := rl.WaitN(4); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected WaitN(4) to wait about 200ms, took %v", elapsed)
+	}
+
+	if err := rl.WaitN(5); err == nil {
+		t.Error("expected error waiting for more events than the burst")
+	}
+}
+
+func TestRateLimiter_WaitContext_Cancel(t *testing.T) {
+	rl := kyro.NewRateLimiterPer(1, time.Hour, 1)
+	if !rl.Allow() {
+		t.Fatal("expected the burst to be available")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := rl.WaitContext(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the wait to return promptly after the cancellation, took %v", elapsed)
+	}
+}
+
+func TestRateLimiter_SetLimit(t *testing.T) {
+	rl := kyro.NewRateLimiter(1000, 1)
+
+	start := time.Now()
+	for range 5 {
+		if err := rl.Wait(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("expected fast waits at 1000/s, took %v", elapsed)
+	}
+
+	rl.SetLimit(20)
+
+	start = time.Now()
+	for range 4 {
+		if err := rl.Wait(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// At 20/s every wait takes 50ms.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected slower waits after lowering the rate, took %v", elapsed)
+	}
+}
+
+func TestRateLimiter_SetBurst(t *testing.T) {
+	rl := kyro.NewRateLimiterPer(1, time.Hour, 1)
+	rl.SetBurst(3)
+
+	// The burst only raises the cap; the tokens refill at the configured rate.
+	if !rl.Allow() || rl.Allow() {
+		t.Error("expected only the accumulated token to be available after raising the burst")
+	}
+	if rl.AllowN(4) {
+		t.Error("expected AllowN above the burst to be denied")
+	}
+}
+
+func TestKeyedRateLimiter_KeysAreIndependent(t *testing.T) {
+	rl := kyro.NewKeyedRateLimiter[string](10, 1)
+
+	// Use up the burst of the first key, so that its next event has to wait 100ms.
+	if err := rl.Wait("a.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := time.Now()
+	if err := rl.Wait("b.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("expected the second key not to be throttled, took %v", elapsed)
+	}
+
+	start = time.Now()
+	if err := rl.Wait("a.example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the first key to be throttled, took %v", elapsed)
+	}
+
+	if rl.Limiter("a.example.com") != rl.Limiter("a.example.com") {
+		t.Error("expected the limiter of a key to be cached")
+	}
+}
+
+func TestRateLimiter_ReserveDelay(t *testing.T) {
+	rl := kyro.NewRateLimiterPer(1, time.Hour, 2)
+
+	if delay := rl.ReserveDelay(); delay != 0 {
+		t.Errorf("expected no delay with a full burst, got %v", delay)
+	}
+
+	rl.Allow()
+	if delay := rl.ReserveDelay(); delay != 0 {
+		t.Errorf("expected no delay with one event left, got %v", delay)
+	}
+
+	rl.Allow()
+	delay := rl.ReserveDelay()
+	if delay < 59*time.Minute {
+		t.Errorf("expected a delay of about an hour once the burst is used up, got %v", delay)
+	}
+
+	// Asking does not consume any allowance.
+	if again := rl.ReserveDelay(); again > delay {
+		t.Errorf("expected the delay not to grow by asking, got %v after %v", again, delay)
+	}
+}
@@ -1,10 +1,14 @@
 package kyro
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // PipelineStep defines the function signature for a single step in a pipeline.
@@ -17,6 +21,9 @@ type GeneratorStep func() (output any, err error)
 
 var errExit error = errors.New("exit error")
 
+// ErrStepTimeout is returned by a TimeoutStep whose step did not finish in time.
+var ErrStepTimeout = errors.New("step timed out")
+
 // Execute runs a generator step followed by a pipeline step.
 // It first calls the generator to get the initial input, and then passes this
 // input to the pipeline step. It returns the output of the pipeline step or an error.
@@ -24,6 +31,111 @@ func Execute(pipeline PipelineStep) (output any, err error) {
 	return pipeline(nil, nil)
 }
 
+// ExecuteWith runs the pipeline like Execute, but seeds its first step with the given input.
+func ExecuteWith(input any, pipeline PipelineStep) (output any, err error) {
+	return pipeline(input, nil)
+}
+
+// ExecuteEach runs the pipeline once for every input, seeded with the input like ExecuteWith,
+// with up to workers runs at once. If workers is not positive, AutoWorkers decides. The output
+// and error of the run for inputs[i] are returned at index i of the returned slices. Wrap the
+// pipeline in a RecoverStep to keep a panicking run from taking down the others.
+func ExecuteEach[I any](inputs []I, workers int, pipeline PipelineStep) ([]any, []error) {
+	outputs := make([]any, len(inputs))
+	errs := make([]error, len(inputs))
+
+	if workers <= 0 {
+		workers = AutoWorkers(len(inputs))
+	}
+
+	indexCh := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(min(workers, len(inputs)))
+
+	for range min(workers, len(inputs)) {
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				outputs[i], errs[i] = ExecuteWith(inputs[i], pipeline)
+			}
+		}()
+	}
+
+	for i := range inputs {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	return outputs, errs
+}
+
+// ExecuteResult is the outcome of a pipeline run with ExecuteFull.
+type ExecuteResult struct {
+	// Output is the output of the pipeline.
+	Output any
+	// Err is the error of the pipeline.
+	Err error
+	// Duration is the time the whole run took.
+	Duration time.Duration
+	// Traces holds the traces of all steps marked with TraceStep or NamedStep, in the order
+	// the steps completed.
+	Traces []StepTrace
+	// Exited reports whether a sequence was stopped early by ExitOnErrorStep.
+	Exited bool
+}
+
+// ExecuteFull runs the pipeline like Execute and returns everything known about the run,
+// for callers who want to log or monitor it. See ExecuteResult.
+func ExecuteFull(pipeline PipelineStep) ExecuteResult {
+	exec := &execution{ctx: context.Background(), trace: &tracer{}, exited: &atomic.Bool{}}
+
+	start := time.Now()
+	output, err := exec.run(pipeline, nil)
+	duration := time.Since(start)
+
+	exec.trace.mu.Lock()
+	defer exec.trace.mu.Unlock()
+
+	return ExecuteResult{
+		Output:   output,
+		Err:      err,
+		Duration: duration,
+		Traces:   slices.Clone(exec.trace.traces),
+		Exited:   exec.exited.Load(),
+	}
+}
+
+// Hooks are callbacks fired around every step run by a combinator such as InSequence,
+// InParallel and their variants. The index is the position of the step within its
+// combinator. Either callback may be nil. As the steps of parallel combinators run
+// concurrently, the callbacks must be safe for concurrent use when those are involved.
+type Hooks struct {
+	// BeforeStep is called right before the step at index is invoked.
+	BeforeStep func(index int)
+	// AfterStep is called right after the step at index returned, with its output, error
+	// and the time it took.
+	AfterStep func(index int, output any, err error, duration time.Duration)
+}
+
+// ExecuteWithHooks runs the pipeline like Execute and fires hooks around every step.
+// Without any callback set, it is the same as Execute.
+func ExecuteWithHooks(pipeline PipelineStep, hooks Hooks) (output any, err error) {
+	if hooks.BeforeStep == nil && hooks.AfterStep == nil {
+		return Execute(pipeline)
+	}
+	return (&execution{ctx: context.Background(), hooks: &hooks}).run(pipeline, nil)
+}
+
+// ExecuteWithContext runs the pipeline like Execute, but stops it once ctx is cancelled.
+// The context is threaded down to all steps: InSequence does not start further steps and
+// InParallel stops waiting for its outstanding steps. Steps created with FromContextStep
+// or AsPipelineStepWithContext receive the context to abort their own work. If ctx is
+// cancelled before the pipeline completes, ExecuteWithContext returns ctx.Err().
+func ExecuteWithContext(ctx context.Context, pipeline PipelineStep) (output any, err error) {
+	return (&execution{ctx: ctx}).run(pipeline, nil)
+}
+
 // AsGenerator is a generic helper function that converts a function with a specific
 // output type into a GeneratorStep. This is useful when the generator produces
 // a specific type but needs to be used in a pipeline that expects any type.
@@ -44,6 +156,59 @@ func AsPipelineStep[I any, O any](step func(input I, lastErr error) (output O, e
 	}
 }
 
+// ContextStep defines the function signature for a pipeline step that needs the context of
+// the current run, e.g. to abort a request once the run is cancelled. Turn it into a
+// PipelineStep with FromContextStep.
+type ContextStep func(ctx context.Context, input any, lastErr error) (output any, err error)
+
+// FromContextStep converts step into a PipelineStep that hands step the context of the
+// current run, see ExecuteWithContext. When the pipeline is not run with a context, step
+// receives context.Background().
+func FromContextStep(step ContextStep) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		if err := exec.wait(); err != nil {
+			return nil, err
+		}
+
+		return step(exec.context(), input, lastErr)
+	}).pipelineStep()
+}
+
+// AsPipelineStepWithContext works like AsPipelineStep, but additionally hands the context
+// of the current run to step, like FromContextStep.
+func AsPipelineStepWithContext[I any, O any](step func(ctx context.Context, input I, lastErr error) (output O, err error)) PipelineStep {
+	return FromContextStep(func(ctx context.Context, input any, lastErr error) (output any, err error) {
+		return step(ctx, AssertIn[I](input), lastErr)
+	})
+}
+
+// AsPipelineStepOrDefault works like AsPipelineStep, but instead of panicking when the input
+// is not of type I, it skips fn and returns def along with lastErr, so that a malformed value
+// degrades the result instead of crashing the pipeline. A nil input is passed to fn as the
+// zero value of I, as with AssertIn. If the run was cancelled, it returns def with the error
+// of the cancellation without calling fn.
+func AsPipelineStepOrDefault[I any, O any](def O, fn func(input I, lastErr error) (output O, err error)) PipelineStep {
+	return scopedStep(func(exec *execution, value any, lastErr error) (output any, err error) {
+		if err := exec.err(); err != nil {
+			return def, err
+		}
+		if err := exec.wait(); err != nil {
+			return def, err
+		}
+
+		if value == nil {
+			var zeroValue I
+			return fn(zeroValue, lastErr)
+		}
+
+		asserted, ok := value.(I)
+		if !ok {
+			return def, lastErr
+		}
+		return fn(asserted, lastErr)
+	}).pipelineStep()
+}
+
 // AssertIn is a helper function that asserts the type of the input to a specific type.
 // If the assertion fails, it panics with a descriptive error message.
 func AssertIn[T any](input any) T {
@@ -64,15 +229,19 @@ func AssertIn[T any](input any) T {
 // The output of each step becomes the input for the next step.
 // If any step in the sequence returns an error, the InSequence step will return that error immediately.
 func InSequence(steps ...PipelineStep) PipelineStep {
-	return func(input any, lastErr error) (output any, err error) {
-		currentInput := input
+	return scopedStep(func(exec *execution, currentInput any, lastErr error) (output any, err error) {
 		currentErr := lastErr
 		beforeExitErr := currentErr
 
-		for _, step := range steps {
-			currentInput, currentErr = step(currentInput, currentErr)
+		for i, step := range steps {
+			if err := exec.err(); err != nil {
+				return nil, err
+			}
+
+			currentInput, currentErr = exec.callAt(i, step, currentInput, currentErr)
 
 			if currentErr != nil && errors.Is(currentErr, errExit) {
+				exec.markExited()
 				return nil, beforeExitErr
 			}
 
@@ -80,7 +249,39 @@ func InSequence(steps ...PipelineStep) PipelineStep {
 		}
 
 		return currentInput, currentErr
-	}
+	}).pipelineStep()
+}
+
+// InSequenceCollectErrors creates a single PipelineStep that runs a sequence of provided
+// pipeline steps like InSequence, but does not stop at the first error. A failing step is
+// skipped, i.e. the next step receives the last successful output, and its error is recorded.
+// The output is the last successful output and the error joins the errors of all failed steps.
+// This suits best-effort pipelines, e.g. a series of optional enrichment steps.
+func InSequenceCollectErrors(steps ...PipelineStep) PipelineStep {
+	return scopedStep(func(exec *execution, currentInput any, lastErr error) (output any, err error) {
+		var errs []error
+
+		for i, step := range steps {
+			if err := exec.err(); err != nil {
+				return nil, err
+			}
+
+			stepOutput, stepErr := exec.callAt(i, step, currentInput, lastErr)
+			if errors.Is(stepErr, errExit) {
+				exec.markExited()
+				break
+			}
+
+			if stepErr != nil {
+				errs = append(errs, stepErr)
+				continue
+			}
+
+			currentInput = stepOutput
+		}
+
+		return currentInput, errors.Join(errs...)
+	}).pipelineStep()
 }
 
 // InParallel creates a single PipelineStep that runs multiple provided pipeline steps concurrently
@@ -89,7 +290,29 @@ func InSequence(steps ...PipelineStep) PipelineStep {
 // in the order the steps were provided. If any parallel step returns an error,
 // the InParallel step will return the first error encountered.
 func InParallel(steps ...PipelineStep) PipelineStep {
-	return func(input any, lastErr error) (output any, err error) {
+	return inParallel(0, false, steps)
+}
+
+// InParallelPartial works like InParallel, but keeps the results of the steps that already
+// completed when a step fails. On error the output is a slice []any with one slot per step,
+// holding the results of the completed steps and nil for the failed and unfinished ones.
+func InParallelPartial(steps ...PipelineStep) PipelineStep {
+	return inParallel(0, true, steps)
+}
+
+// InParallelLimited works like InParallel, but runs at most maxConcurrency steps at once.
+// The remaining steps wait until a running step completes, so no more than maxConcurrency
+// goroutines are running steps at any time. The results keep the order of the provided
+// steps. A maxConcurrency of zero or less runs all steps at once, like InParallel.
+func InParallelLimited(maxConcurrency int, steps ...PipelineStep) PipelineStep {
+	return inParallel(maxConcurrency, false, steps)
+}
+
+// inParallel implements InParallel, InParallelPartial and InParallelLimited. A limit of zero
+// or less does not limit the number of steps running at once. If partial is set, the results
+// completed so far are returned alongside an error.
+func inParallel(limit int, partial bool, steps []PipelineStep) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
 		numSteps := len(steps)
 
 		if numSteps == 0 {
@@ -100,18 +323,235 @@ func InParallel(steps ...PipelineStep) PipelineStep {
 		errCh := make(chan error, numSteps)
 		var wg sync.WaitGroup
 
+		// resultsMu guards results, as steps may still complete while
+		// the partial results are collected after an error.
+		var resultsMu sync.Mutex
+
+		// failed returns the output and error of the step after stepErr occurred.
+		failed := func(stepErr error) (any, error) {
+			repanic(stepErr)
+			if !partial {
+				return nil, stepErr
+			}
+
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+			return slices.Clone(results), stepErr
+		}
+
+		// sem holds a slot for every running step. Without a limit it stays nil
+		// and steps are launched without waiting for a slot.
+		var sem chan struct{}
+		if limit > 0 && limit < numSteps {
+			sem = make(chan struct{}, limit)
+		}
+
+		// The slot of the step running this combinator, if any, is given up while
+		// waiting for the steps below, and taken again after stop is closed.
+		defer exec.yieldSlot()()
+
+		// stop is closed once the step returns, so that no further steps
+		// are launched after an error or a cancellation.
+		stop := make(chan struct{})
+		defer close(stop)
+
+		done := make(chan struct{})
+		go func() {
+			defer func() {
+				wg.Wait()
+				close(done)
+			}()
+
+			for i, step := range steps {
+				if sem != nil {
+					select {
+					case sem <- struct{}{}:
+					case <-stop:
+						return
+					}
+				}
+
+				stepExec, ok := exec.acquireSlot(stop)
+				if !ok {
+					if sem != nil {
+						<-sem
+					}
+					return
+				}
+
+				wg.Add(1)
+				go func(index int, s PipelineStep) {
+					defer wg.Done()
+					defer stepExec.releaseSlot()
+					if sem != nil {
+						defer func() { <-sem }()
+					}
+
+					out, stepErr := stepExec.recovering(func() (any, error) {
+						return stepExec.callAt(index, s, input, lastErr)
+					})
+					if stepErr != nil {
+						select {
+						case errCh <- stepErr:
+						default:
+							// Error channel is full, another error has already been sent.
+							// We prioritize the first error.
+						}
+						return
+					}
+
+					resultsMu.Lock()
+					results[index] = out
+					resultsMu.Unlock()
+				}(i, step)
+			}
+		}()
+
+		select {
+		case stepErr := <-errCh:
+			return failed(stepErr)
+		case <-done:
+			// A step may have failed right before the last one completed,
+			// in which case both channels are ready at the same time.
+			select {
+			case stepErr := <-errCh:
+				return failed(stepErr)
+			default:
+				return results, nil
+			}
+		case <-exec.done():
+			// The outstanding steps keep running until they return on their own,
+			// but as both channels are buffered, none of them blocks forever.
+			return nil, exec.err()
+		}
+	}).pipelineStep()
+}
+
+// InParallelQuorum creates a single PipelineStep that runs the provided steps concurrently
+// with the same input and returns as soon as k of them succeeded, e.g. for redundant reads
+// where the fastest majority suffices. The output is a slice []any holding the results of
+// the first k successful steps in the order they completed. Once the quorum is reached or
+// can no longer be reached, the context handed to the outstanding steps is cancelled, see
+// AsPipelineStepWithContext. If more than len(steps)-k steps fail, the error joins their
+// errors with errors.Join.
+func InParallelQuorum(k int, steps ...PipelineStep) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		if k <= 0 {
+			return []any{}, nil
+		}
+		if k > len(steps) {
+			return nil, fmt.Errorf("quorum of %d cannot be reached by %d steps", k, len(steps))
+		}
+
+		// The slot of the step running this combinator, if any, is given up while
+		// waiting for the steps below, and taken again after they are cancelled.
+		defer exec.yieldSlot()()
+
+		ctx, cancel := context.WithCancel(exec.context())
+		defer cancel()
+		quorumExec := exec.withContext(ctx)
+
+		type result struct {
+			output any
+			err    error
+		}
+
+		// resultCh is buffered, so the steps still running when
+		// the quorum is decided never block on sending their result.
+		resultCh := make(chan result, len(steps))
+
+		for i, step := range steps {
+			go func(index int, s PipelineStep) {
+				stepExec, ok := quorumExec.acquireSlot(nil)
+				if !ok {
+					resultCh <- result{err: ctx.Err()}
+					return
+				}
+				defer stepExec.releaseSlot()
+
+				out, stepErr := stepExec.recovering(func() (any, error) {
+					return stepExec.callAt(index, s, input, lastErr)
+				})
+				resultCh <- result{output: out, err: stepErr}
+			}(i, step)
+		}
+
+		results := make([]any, 0, k)
+		var errs []error
+
+		for range steps {
+			select {
+			case r := <-resultCh:
+				if r.err != nil {
+					repanic(r.err)
+					errs = append(errs, r.err)
+					if len(errs) > len(steps)-k {
+						return nil, errors.Join(errs...)
+					}
+					continue
+				}
+
+				results = append(results, r.output)
+				if len(results) == k {
+					return results, nil
+				}
+			case <-exec.done():
+				return nil, exec.err()
+			}
+		}
+
+		return results, errors.Join(errs...)
+	}).pipelineStep()
+}
+
+// BranchStep creates a PipelineStep that routes its input to ifTrue if predicate
+// returns true for it and to ifFalse otherwise. The output of the chosen branch becomes
+// the output of the step, the other branch is never invoked.
+func BranchStep(predicate func(input any) bool, ifTrue PipelineStep, ifFalse PipelineStep) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		if predicate(input) {
+			return exec.call(ifTrue, input, lastErr)
+		}
+
+		return exec.call(ifFalse, input, lastErr)
+	}).pipelineStep()
+}
+
+// InParallelCollect works like InParallel, but instead of failing fast it waits for all
+// steps to complete. The output is a slice []any holding the results of the steps in the
+// order they were provided, with nil for the steps that failed. The error joins the errors
+// of all failed steps with errors.Join, or is nil if every step succeeded.
+func InParallelCollect(steps ...PipelineStep) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		if len(steps) == 0 {
+			return nil, nil
+		}
+
+		results := make([]any, len(steps))
+		errs := make([]error, len(steps))
+		var wg sync.WaitGroup
+
+		// The slot of the step running this combinator, if any,
+		// is given up while waiting for the steps below.
+		defer exec.yieldSlot()()
+
 		for i, step := range steps {
 			wg.Add(1)
 			go func(index int, s PipelineStep) {
 				defer wg.Done()
-				out, stepErr := s(input, lastErr)
+
+				stepExec, ok := exec.acquireSlot(nil)
+				if !ok {
+					errs[index] = exec.err()
+					return
+				}
+				defer stepExec.releaseSlot()
+
+				out, stepErr := stepExec.recovering(func() (any, error) {
+					return stepExec.callAt(index, s, input, lastErr)
+				})
 				if stepErr != nil {
-					select {
-					case errCh <- stepErr:
-					default:
-						// Error channel is full, another error has already been sent.
-						// We prioritize the first error.
-					}
+					errs[index] = stepErr
 					return
 				}
 				results[index] = out
@@ -125,16 +565,56 @@ func InParallel(steps ...PipelineStep) PipelineStep {
 		}()
 
 		select {
-		case stepErr := <-errCh:
-			return nil, stepErr
 		case <-done:
-			return results, nil
+			for _, stepErr := range errs {
+				repanic(stepErr)
+			}
+			return results, errors.Join(errs...)
+		case <-exec.done():
+			return nil, exec.err()
 		}
-	}
+	}).pipelineStep()
 }
 
 /* ======================== STEPS ======================== */
 
+// RetryStep creates a PipelineStep that invokes step up to attempts times as long as it
+// returns an error, sleeping backoff between the attempts. It returns the output of the
+// first successful attempt or the last error if all attempts fail. An error the step merely
+// passes through from a previous step is returned right away, as retrying cannot fix it. The
+// step is always invoked at least once, even if attempts is zero or negative.
+func RetryStep(step PipelineStep, attempts int, backoff time.Duration) PipelineStep {
+	return retryStep(step, attempts, backoff, 1)
+}
+
+// RetryStepExp works like RetryStep, but doubles the backoff after every failed attempt.
+func RetryStepExp(step PipelineStep, attempts int, backoff time.Duration) PipelineStep {
+	return retryStep(step, attempts, backoff, 2)
+}
+
+// retryStep implements RetryStep and RetryStepExp. The backoff is multiplied by factor
+// after every failed attempt. Waiting for the next attempt is aborted when the run is cancelled.
+func retryStep(step PipelineStep, attempts int, backoff time.Duration, factor int) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		delay := backoff
+
+		for attempt := 1; ; attempt++ {
+			output, err = exec.call(step, input, lastErr)
+			if err == nil || err == lastErr || attempt >= attempts {
+				return output, err
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-exec.done():
+				return nil, exec.err()
+			}
+
+			delay *= time.Duration(factor)
+		}
+	}).pipelineStep()
+}
+
 // RemoveFileStep creates a PipelineStep that removes the file at the given path
 // if it exists. The step passes the input and error through, only returning
 // an error if the file removal fails.
@@ -150,6 +630,239 @@ func RemoveFileStep(path string) PipelineStep {
 	})
 }
 
+// TimeoutStep creates a PipelineStep that runs step in a separate goroutine and returns
+// an error wrapping ErrStepTimeout if it does not finish within d. The step keeps running
+// in the background after a timeout and its eventual result is discarded. When the
+// pipeline runs with a context, the context seen by step is cancelled on timeout.
+func TimeoutStep(step PipelineStep, d time.Duration) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		ctx, cancel := context.WithTimeout(exec.context(), d)
+		defer cancel()
+
+		type result struct {
+			output any
+			err    error
+		}
+
+		// done is buffered and never closed, so a step finishing after the
+		// timeout can still deliver its result without blocking or panicking.
+		done := make(chan result, 1)
+
+		go func() {
+			stepExec := exec.withContext(ctx)
+			output, err := stepExec.recovering(func() (any, error) {
+				return stepExec.call(step, input, lastErr)
+			})
+			done <- result{output: output, err: err}
+		}()
+
+		select {
+		case r := <-done:
+			repanic(r.err)
+			return r.output, r.err
+		case <-ctx.Done():
+			if err := exec.err(); err != nil {
+				return nil, err
+			}
+
+			return nil, fmt.Errorf("%w after %s", ErrStepTimeout, d)
+		}
+	}).pipelineStep()
+}
+
+// PanicError is returned by a RecoverStep whose step panicked.
+type PanicError struct {
+	// Value is the value the step panicked with.
+	Value any
+	// Stack is the stack trace of the goroutine at the time of the panic.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic in step: %v\n%s", e.Value, e.Stack)
+}
+
+// RecoverStep creates a PipelineStep that recovers from a panic in step, e.g. raised by
+// AssertIn on a mis-wired step, and returns it as a *PanicError including the stack trace
+// instead of crashing the program. Panics of steps run concurrently by nested combinators
+// such as InParallel are recovered as well, with the stack trace of their own goroutine.
+func RecoverStep(step PipelineStep) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				value, stack := recovered(r)
+				output, err = nil, &PanicError{Value: value, Stack: stack}
+			}
+		}()
+
+		return exec.forwardingPanics().call(step, input, lastErr)
+	}).pipelineStep()
+}
+
+// RepeatStep creates a PipelineStep that runs step the given number of times, passing the
+// output of each iteration as the input of the next one. It stops early and returns the
+// error of the failing iteration. If times is zero or less, the input and error are
+// returned unchanged.
+func RepeatStep(step PipelineStep, times int) PipelineStep {
+	return scopedStep(func(exec *execution, output any, lastErr error) (_ any, err error) {
+		err = lastErr
+
+		for range times {
+			if err := exec.err(); err != nil {
+				return nil, err
+			}
+
+			output, err = exec.call(step, output, err)
+			if err != nil {
+				return output, err
+			}
+		}
+
+		return output, err
+	}).pipelineStep()
+}
+
+// ValidationError is returned by Validate for a pipeline that panicked on the sample input,
+// typically because AssertIn found a value of another type than a step expects.
+type ValidationError struct {
+	// Step is the name of the innermost NamedStep or TraceStep the panic occurred in,
+	// or empty if it occurred outside of any named step.
+	Step string
+	// Value is the value the pipeline panicked with.
+	Value any
+}
+
+func (e *ValidationError) Error() string {
+	if e.Step == "" {
+		return fmt.Sprintf("pipeline panicked: %v", e.Value)
+	}
+	return fmt.Sprintf("step %q panicked: %v", e.Step, e.Value)
+}
+
+// Validate dry-runs the pipeline with sampleInput to find steps whose types do not fit
+// together before the pipeline is used for real, e.g. at startup. A panic, such as the one
+// raised by AssertIn on a type mismatch, is returned as a *ValidationError naming the step it
+// occurred in if the step is named with NamedStep. Errors returned by the steps are not
+// reported, as they do not hint at a mismatch. The steps are really invoked, so the pipeline
+// should be free of side effects for the sample input. Panics of steps run concurrently by
+// combinators such as InParallel are reported as well.
+func Validate(pipeline PipelineStep, sampleInput any) (err error) {
+	exec := &execution{ctx: context.Background(), panics: &panicSite{}, forwardPanics: true}
+
+	defer func() {
+		if r := recover(); r != nil {
+			value, _ := recovered(r)
+			err = &ValidationError{Step: exec.panics.step, Value: value}
+		}
+	}()
+
+	exec.call(pipeline, sampleInput, nil)
+	return nil
+}
+
+// NamedStep creates a PipelineStep that annotates errors raised by step with its name,
+// as in `step "fetch": connection refused`. The error is wrapped with %w, so errors.Is and
+// errors.As still see the original error. An error the step merely passes through from
+// a previous step is returned unchanged. The step is also traced under its name, see TraceStep.
+func NamedStep(name string, step PipelineStep) PipelineStep {
+	traced := TraceStep(name, step)
+
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		output, err = exec.call(traced, input, lastErr)
+		if err != nil && err != lastErr {
+			return output, fmt.Errorf("step %q: %w", name, err)
+		}
+
+		return output, err
+	}).pipelineStep()
+}
+
+// TapStep creates a PipelineStep that calls fn with its input and error for side effects
+// such as logging or metrics, and then returns both unchanged.
+func TapStep(fn func(input any, err error)) PipelineStep {
+	return func(input any, lastErr error) (output any, err error) {
+		fn(input, lastErr)
+		return input, lastErr
+	}
+}
+
+// CollectStep creates a PipelineStep that unpacks the two-element []any produced by
+// InParallel into typed arguments for fn. Instead of panicking, it returns an error if
+// the input is not a slice of two elements or an element has an unexpected type. A nil
+// element is passed as the zero value of its type. An error of the previous step is
+// passed on alongside the output of fn.
+func CollectStep[A any, B any](fn func(A, B) (any, error)) PipelineStep {
+	return collectStep(2, func(results []any) (any, error) {
+		a, errA := collectArg[A](results, 0)
+		b, errB := collectArg[B](results, 1)
+		if err := errors.Join(errA, errB); err != nil {
+			return nil, err
+		}
+		return fn(a, b)
+	})
+}
+
+// CollectStep3 works like CollectStep for the three-element []any of InParallel.
+func CollectStep3[A any, B any, C any](fn func(A, B, C) (any, error)) PipelineStep {
+	return collectStep(3, func(results []any) (any, error) {
+		a, errA := collectArg[A](results, 0)
+		b, errB := collectArg[B](results, 1)
+		c, errC := collectArg[C](results, 2)
+		if err := errors.Join(errA, errB, errC); err != nil {
+			return nil, err
+		}
+		return fn(a, b, c)
+	})
+}
+
+// CollectStep4 works like CollectStep for the four-element []any of InParallel.
+func CollectStep4[A any, B any, C any, D any](fn func(A, B, C, D) (any, error)) PipelineStep {
+	return collectStep(4, func(results []any) (any, error) {
+		a, errA := collectArg[A](results, 0)
+		b, errB := collectArg[B](results, 1)
+		c, errC := collectArg[C](results, 2)
+		d, errD := collectArg[D](results, 3)
+		if err := errors.Join(errA, errB, errC, errD); err != nil {
+			return nil, err
+		}
+		return fn(a, b, c, d)
+	})
+}
+
+// collectStep implements the CollectStep variants. It checks that the input is a []any
+// of the given arity before handing it to fn.
+func collectStep(arity int, fn func(results []any) (any, error)) PipelineStep {
+	return func(input any, lastErr error) (output any, err error) {
+		results, ok := input.([]any)
+		if !ok || len(results) != arity {
+			return nil, errors.Join(lastErr, fmt.Errorf("expected %d parallel results, got %T of length %d", arity, input, len(results)))
+		}
+
+		output, err = fn(results)
+		if err != nil {
+			return nil, err
+		}
+
+		return output, lastErr
+	}
+}
+
+// collectArg returns the element of results at index as a T, or an error if it has a
+// different type. A nil element yields the zero value of T.
+func collectArg[T any](results []any, index int) (T, error) {
+	var zeroValue T
+	if results[index] == nil {
+		return zeroValue, nil
+	}
+
+	value, ok := results[index].(T)
+	if !ok {
+		return zeroValue, fmt.Errorf("expected type %T for parallel result %d, got %T", zeroValue, index, results[index])
+	}
+
+	return value, nil
+}
+
 // ExitOnErrorStep creates a PipelineStep that immediately stops the pipeline
 // if the previous step returned an error.
 func ExitOnErrorStep() PipelineStep {
@@ -163,19 +876,20 @@ func ExitOnErrorStep() PipelineStep {
 }
 
 // TakeFirstStep creates a PipelineStep that takes the first N elements
-// from a slice.  The value of n is the number of elements to take, so ids[:n]
-// will take the first n elements.
+// from a slice. If the slice holds fewer than n elements, all of them are
+// taken, and a negative n takes none, so an empty input yields an empty result.
 func TakeFirstStep[T any](n int) PipelineStep {
 	return AsPipelineStep(func(ids []T, err error) ([]T, error) {
-		return ids[:n], err
+		return ids[:min(max(n, 0), len(ids))], err
 	})
 }
 
 // TakeLastStep creates a PipelineStep that takes the last N elements
-// from a slice
+// from a slice. If the slice holds fewer than n elements, all of them are
+// taken, and a negative n takes none, so an empty input yields an empty result.
 func TakeLastStep[T any](n int) PipelineStep {
 	return AsPipelineStep(func(ids []T, err error) ([]T, error) {
-		return ids[len(ids)-n:], err
+		return ids[len(ids)-min(max(n, 0), len(ids)):], err
 	})
 }
 
@@ -191,3 +905,129 @@ func TakeSubsetStep[T any](start, end int) PipelineStep {
 		return ids[start:end], err
 	})
 }
+
+// PagedGenerator creates a PipelineStep that generates a []T by repeatedly calling
+// fetchPage until it returns an empty next cursor. The first page is requested with
+// an empty cursor, and the items of all pages are concatenated in the order they were
+// fetched. If fetchPage returns an error, the items fetched so far are returned with it.
+func PagedGenerator[T any](fetchPage func(cursor string) (items []T, nextCursor string, err error)) PipelineStep {
+	return AsPipelineGenerator(func() ([]T, error) {
+		var all []T
+		cursor := ""
+
+		for {
+			items, nextCursor, err := fetchPage(cursor)
+			all = append(all, items...)
+
+			if err != nil {
+				return all, err
+			}
+
+			if nextCursor == "" {
+				return all, nil
+			}
+
+			cursor = nextCursor
+		}
+	})
+}
+
+// ToMapStep creates a PipelineStep that turns a []T into a map[K]T keyed by keyFn.
+// If several elements share a key, the last one wins.
+func ToMapStep[T any, K comparable](keyFn func(T) K) PipelineStep {
+	return AsPipelineStep(func(items []T, err error) (map[K]T, error) {
+		return Associate(items, keyFn), err
+	})
+}
+
+// ToSliceStep creates a PipelineStep that turns a map[K]V into a []V holding its values.
+// The order of the values in the resulting slice is not specified.
+func ToSliceStep[K comparable, V any]() PipelineStep {
+	return AsPipelineStep(func(m map[K]V, err error) ([]V, error) {
+		return MapValues(m), err
+	})
+}
+
+// MapStep creates a PipelineStep that applies fn to every element of a []T and returns
+// the results as a []V. It stops at the first element for which fn returns an error and
+// returns that error. An empty or nil input yields an empty slice.
+func MapStep[T any, V any](fn func(T) (V, error)) PipelineStep {
+	return AsPipelineStep(func(items []T, err error) ([]V, error) {
+		result := make([]V, len(items))
+		for i, item := range items {
+			value, fnErr := fn(item)
+			if fnErr != nil {
+				return nil, fnErr
+			}
+			result[i] = value
+		}
+
+		return result, err
+	})
+}
+
+// DecodeStep creates a PipelineStep that decodes raw records with the given decode function,
+// e.g. JSON lines, protobuf or a custom binary framing. A [][]byte input is decoded into a
+// []T and a single []byte input into a T. A nil input yields an empty []T, any other input
+// causes a panic like AssertIn.
+// The first record failing to decode stops the step with its error.
+func DecodeStep[T any](decode func([]byte) (T, error)) PipelineStep {
+	return func(input any, lastErr error) (any, error) {
+		switch records := input.(type) {
+		case nil:
+			return []T{}, lastErr
+		case []byte:
+			value, err := decode(records)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode record: %w", err)
+			}
+			return value, lastErr
+		case [][]byte:
+			values := make([]T, len(records))
+			for i, record := range records {
+				value, err := decode(record)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decode record %d: %w", i, err)
+				}
+				values[i] = value
+			}
+			return values, lastErr
+		default:
+			panic(fmt.Sprintf("expected type [][]byte or []byte, got %T", input))
+		}
+	}
+}
+
+// EnrichStep creates a PipelineStep that enriches a []T with a single batched lookup instead
+// of one lookup per item. It collects the distinct keys of all items with keyFn, looks them
+// up at once with batchLookup and replaces every item whose key was found by the result of
+// apply. Items whose key is missing from the lookup result are kept unchanged. If the lookup
+// fails, the step returns its error.
+func EnrichStep[T any, K comparable, V any](keyFn func(T) K, batchLookup func([]K) (map[K]V, error), apply func(T, V) T) PipelineStep {
+	return AsPipelineStep(func(items []T, err error) ([]T, error) {
+		keys := make([]K, 0, len(items))
+		seen := make(map[K]struct{}, len(items))
+		for _, item := range items {
+			key := keyFn(item)
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				keys = append(keys, key)
+			}
+		}
+
+		values, lookupErr := batchLookup(keys)
+		if lookupErr != nil {
+			return nil, lookupErr
+		}
+
+		result := make([]T, len(items))
+		for i, item := range items {
+			if value, ok := values[keyFn(item)]; ok {
+				item = apply(item, value)
+			}
+			result[i] = item
+		}
+
+		return result, err
+	})
+}
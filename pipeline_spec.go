@@ -0,0 +1,68 @@
+package kyro
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrUnknownStep is wrapped by the error returned by BuildPipeline for a step that was not
+// registered with RegisterStep.
+var ErrUnknownStep = errors.New("unknown step")
+
+// StepFactory creates a PipelineStep from the arguments of a StepSpec. The arguments are
+// typically decoded from JSON or YAML, so numbers may arrive as float64.
+type StepFactory func(args map[string]any) (PipelineStep, error)
+
+// StepSpec describes a step of a pipeline built with BuildPipeline by the name of its
+// registered factory and the arguments passed to it. It can be decoded from configuration.
+type StepSpec struct {
+	Name string         `json:"name" yaml:"name"`
+	Args map[string]any `json:"args,omitempty" yaml:"args,omitempty"`
+}
+
+var (
+	stepFactoriesMutex sync.RWMutex
+	stepFactories      = make(map[string]StepFactory)
+)
+
+// RegisterStep makes a step factory available to BuildPipeline under the given name. It is
+// meant to be called during initialization and panics if the name is already registered or
+// the factory is nil, as that is a programming error.
+func RegisterStep(name string, factory StepFactory) {
+	stepFactoriesMutex.Lock()
+	defer stepFactoriesMutex.Unlock()
+
+	if factory == nil {
+		panic(fmt.Sprintf("kyro: step factory %q is nil", name))
+	}
+	if _, exists := stepFactories[name]; exists {
+		panic(fmt.Sprintf("kyro: step %q registered twice", name))
+	}
+	stepFactories[name] = factory
+}
+
+// BuildPipeline creates a pipeline running the steps described by spec in sequence, as with
+// InSequence, so that pipelines can be defined in configuration instead of code. Every step
+// is created by the factory registered under its name and wrapped in a NamedStep, so that its
+// errors and traces carry the name. It fails if a step is not registered or its factory fails.
+func BuildPipeline(spec []StepSpec) (PipelineStep, error) {
+	stepFactoriesMutex.RLock()
+	defer stepFactoriesMutex.RUnlock()
+
+	steps := make([]PipelineStep, 0, len(spec))
+	for i, stepSpec := range spec {
+		factory, exists := stepFactories[stepSpec.Name]
+		if !exists {
+			return nil, fmt.Errorf("step %d: %w %q", i, ErrUnknownStep, stepSpec.Name)
+		}
+
+		step, err := factory(stepSpec.Args)
+		if err != nil {
+			return nil, fmt.Errorf("step %d (%q): %w", i, stepSpec.Name, err)
+		}
+		steps = append(steps, NamedStep(stepSpec.Name, step))
+	}
+
+	return InSequence(steps...), nil
+}
@@ -0,0 +1,85 @@
+package kyro_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func init() {
+	kyro.RegisterStep("spec-test/add", func(args map[string]any) (kyro.PipelineStep, error) {
+		n, ok := args["n"].(float64)
+		if !ok {
+			return nil, fmt.Errorf("argument n must be a number, got %v", args["n"])
+		}
+		return kyro.AsPipelineStep(func(input int, lastErr error) (int, error) {
+			return input + int(n), lastErr
+		}), nil
+	})
+
+	kyro.RegisterStep("spec-test/format", func(args map[string]any) (kyro.PipelineStep, error) {
+		prefix, _ := args["prefix"].(string)
+		return kyro.AsPipelineStep(func(input int, lastErr error) (string, error) {
+			return fmt.Sprintf("%s%d", prefix, input), lastErr
+		}), nil
+	})
+}
+
+func TestBuildPipeline_FromJSON(t *testing.T) {
+	config := `[
+		{"name": "spec-test/add", "args": {"n": 2}},
+		{"name": "spec-test/add", "args": {"n": 3}},
+		{"name": "spec-test/format", "args": {"prefix": "#"}}
+	]`
+
+	var spec []kyro.StepSpec
+	if err := json.Unmarshal([]byte(config), &spec); err != nil {
+		t.Fatalf("failed to decode spec: %v", err)
+	}
+
+	p, err := kyro.BuildPipeline(spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	output, err := kyro.ExecuteWith(1, p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if output != "#6" {
+		t.Errorf("expected output '#6', got %v", output)
+	}
+}
+
+func TestBuildPipeline_UnknownStep(t *testing.T) {
+	_, err := kyro.BuildPipeline([]kyro.StepSpec{{Name: "spec-test/add", Args: map[string]any{"n": 1.0}}, {Name: "spec-test/missing"}})
+
+	if !errors.Is(err, kyro.ErrUnknownStep) {
+		t.Errorf("expected ErrUnknownStep, got: %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "spec-test/missing") {
+		t.Errorf("expected error to name the missing step, got: %v", err)
+	}
+}
+
+func TestBuildPipeline_FactoryError(t *testing.T) {
+	_, err := kyro.BuildPipeline([]kyro.StepSpec{{Name: "spec-test/add", Args: map[string]any{"n": "two"}}})
+
+	if err == nil || !strings.Contains(err.Error(), "argument n must be a number") {
+		t.Errorf("expected factory error, got: %v", err)
+	}
+}
+
+func TestRegisterStep_Duplicate(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("expected panic registering a step twice")
+		}
+	}()
+
+	kyro.RegisterStep("spec-test/format", func(args map[string]any) (kyro.PipelineStep, error) { return nil, nil })
+}
@@ -1,10 +1,14 @@
 package kyro_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -63,6 +67,110 @@ func TestExecute_Success(t *testing.T) {
 	}
 }
 
+func TestExecuteEach_OrderedByInput(t *testing.T) {
+	inputs := make([]int, 20)
+	for i := range inputs {
+		inputs[i] = i
+	}
+
+	p := kyro.InSequence(
+		kyro.AsPipelineStep(func(input int, lastErr error) (int, error) {
+			// Later inputs finish first, which scrambles the completion order.
+			time.Sleep(time.Duration(len(inputs)-input) * time.Millisecond)
+			if input == 7 {
+				return 0, errors.New("unlucky")
+			}
+			return input * 2, nil
+		}),
+		kyro.AsPipelineStep(func(input int, lastErr error) (string, error) {
+			if lastErr != nil {
+				return "", lastErr
+			}
+			return fmt.Sprintf("#%d", input), nil
+		}),
+	)
+
+	outputs, errs := kyro.ExecuteEach(inputs, 4, p)
+
+	if len(outputs) != len(inputs) || len(errs) != len(inputs) {
+		t.Fatalf("expected %d outputs and errors, got %d and %d", len(inputs), len(outputs), len(errs))
+	}
+	for i := range inputs {
+		if i == 7 {
+			if errs[i] == nil {
+				t.Errorf("expected error for input 7, got output %v", outputs[i])
+			}
+			continue
+		}
+		if errs[i] != nil {
+			t.Errorf("unexpected error for input %d: %v", i, errs[i])
+		}
+		if expected := fmt.Sprintf("#%d", i*2); outputs[i] != expected {
+			t.Errorf("expected output %q at index %d, got %v", expected, i, outputs[i])
+		}
+	}
+}
+
+func TestExecuteFull_ErroringRun(t *testing.T) {
+	stepErr := errors.New("fetch failed")
+
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.NamedStep("add", kyro.AsPipelineStep(addOneStep)),
+		kyro.NamedStep("fetch", func(input any, err error) (any, error) {
+			time.Sleep(5 * time.Millisecond)
+			return nil, stepErr
+		}),
+		kyro.ExitOnErrorStep(),
+		kyro.NamedStep("format", kyro.AsPipelineStep(intToStringStep)),
+	)
+
+	result := kyro.ExecuteFull(p)
+
+	if !errors.Is(result.Err, stepErr) {
+		t.Errorf("expected %v, got: %v", stepErr, result.Err)
+	}
+	if result.Output != nil {
+		t.Errorf("expected nil output, got %v", result.Output)
+	}
+	if !result.Exited {
+		t.Error("expected the run to have exited via ExitOnErrorStep")
+	}
+	if result.Duration < 5*time.Millisecond {
+		t.Errorf("expected a duration of at least 5ms, got %v", result.Duration)
+	}
+	if len(result.Traces) != 2 || result.Traces[0].Name != "add" || result.Traces[1].Name != "fetch" {
+		t.Fatalf("expected traces of add and fetch, got %+v", result.Traces)
+	}
+	if !errors.Is(result.Traces[1].Err, stepErr) {
+		t.Errorf("expected the fetch trace to carry its error, got %v", result.Traces[1].Err)
+	}
+}
+
+func TestExecuteFull_Success(t *testing.T) {
+	result := kyro.ExecuteFull(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.AsPipelineStep(intToStringStep),
+	))
+
+	if result.Err != nil || result.Output != "10" || result.Exited || len(result.Traces) != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
+func TestExecuteFull_RawSteps(t *testing.T) {
+	result := kyro.ExecuteFull(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		func(input any, err error) (any, error) {
+			return input.(int) + 1, err
+		},
+	))
+
+	if result.Err != nil || result.Output != 11 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
+
 func TestExecute_GeneratorError(t *testing.T) {
 	p := kyro.InSequence(
 		kyro.AsPipelineGenerator(errorGenerator),
@@ -195,6 +303,47 @@ func TestAssertIn_Failure_Panics(t *testing.T) {
 	}()
 }
 
+func TestAsPipelineStepOrDefault(t *testing.T) {
+	var called bool
+	step := kyro.AsPipelineStepOrDefault("fallback", func(input int, lastErr error) (string, error) {
+		called = true
+		return fmt.Sprintf("got %d", input), nil
+	})
+
+	output, err := step("not an int", nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != "fallback" || called {
+		t.Errorf("expected default without calling the step, got %v (called: %v)", output, called)
+	}
+
+	output, err = step(42, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != "got 42" {
+		t.Errorf("expected 'got 42', got %v", output)
+	}
+}
+
+func TestAsPipelineStepOrDefault_InSequenceWithContext(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(func() (bool, error) { return true, nil }),
+		kyro.AsPipelineStepOrDefault(-1, func(input int, lastErr error) (int, error) {
+			return input * 2, nil
+		}),
+	)
+
+	output, err := kyro.ExecuteWithContext(context.Background(), p)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != -1 {
+		t.Errorf("expected default -1, got %v", output)
+	}
+}
+
 func TestInSequence_Success(t *testing.T) {
 	step1 := kyro.AsPipelineStep(addOneStep)
 	step2 := kyro.AsPipelineStep(multiplyByTwoStep)
@@ -265,6 +414,30 @@ func TestInSequence_SingleStep(t *testing.T) {
 	}
 }
 
+func TestInSequenceCollectErrors_ReportsAllErrors(t *testing.T) {
+	errFirst := errors.New("first enrichment failed")
+	errThird := errors.New("third enrichment failed")
+
+	sequence := kyro.InSequenceCollectErrors(
+		func(input any, err error) (any, error) {
+			return nil, errFirst
+		},
+		kyro.AsPipelineStep(addOneStep),
+		func(input any, err error) (any, error) {
+			return nil, errThird
+		},
+	)
+
+	output, err := sequence(5, nil)
+
+	if !errors.Is(err, errFirst) || !errors.Is(err, errThird) {
+		t.Errorf("expected both errors to be reported, got: %v", err)
+	}
+	if output != 6 {
+		t.Errorf("expected output 6, got %v", output)
+	}
+}
+
 func TestInParallel_Success(t *testing.T) {
 	step1 := kyro.AsPipelineStep(func(input int, err error) (string, error) {
 		return fmt.Sprintf("step1: %d", input), nil
@@ -723,3 +896,1219 @@ func TestErrorHandler_Sequential(t *testing.T) {
 		t.Errorf("expected output 'step 2 output', got %v", output)
 	}
 }
+
+func TestPagedGenerator_ThreePages(t *testing.T) {
+	pages := map[string]struct {
+		items []int
+		next  string
+	}{
+		"":       {items: []int{1, 2}, next: "page-2"},
+		"page-2": {items: []int{3, 4}, next: "page-3"},
+		"page-3": {items: []int{5}, next: ""},
+	}
+
+	var cursors []string
+	generator := kyro.PagedGenerator(func(cursor string) ([]int, string, error) {
+		cursors = append(cursors, cursor)
+		page := pages[cursor]
+		return page.items, page.next, nil
+	})
+
+	output, err := kyro.Execute(generator)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("expected output [1 2 3 4 5], got %v", output)
+	}
+	if !reflect.DeepEqual(cursors, []string{"", "page-2", "page-3"}) {
+		t.Errorf("expected cursors ['' page-2 page-3], got %v", cursors)
+	}
+}
+
+func TestPagedGenerator_ErrorStopsPaging(t *testing.T) {
+	calls := 0
+	generator := kyro.PagedGenerator(func(cursor string) ([]int, string, error) {
+		calls++
+		if cursor == "page-2" {
+			return nil, "", errors.New("fetch failed")
+		}
+		return []int{1, 2}, "page-2", nil
+	})
+
+	output, err := kyro.Execute(generator)
+
+	if err == nil || err.Error() != "fetch failed" {
+		t.Errorf("expected error 'fetch failed', got: %v", err)
+	}
+	if !reflect.DeepEqual(output, []int{1, 2}) {
+		t.Errorf("expected partial output [1 2], got %v", output)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestToMapStep_ToSliceStep_RoundTrip(t *testing.T) {
+	toMap := kyro.ToMapStep(func(s string) int { return len(s) })
+
+	output, err := kyro.Execute(kyro.InSequence(
+		kyro.AsPipelineGenerator(func() ([]string, error) {
+			return []string{"a", "bb", "ccc"}, nil
+		}),
+		toMap,
+	))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	expectedMap := map[int]string{1: "a", 2: "bb", 3: "ccc"}
+	if !reflect.DeepEqual(output, expectedMap) {
+		t.Errorf("expected %v, got %v", expectedMap, output)
+	}
+
+	output, err = kyro.ToSliceStep[int, string]()(output, nil)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	values := kyro.AssertIn[[]string](output)
+	slices.Sort(values)
+	if !reflect.DeepEqual(values, []string{"a", "bb", "ccc"}) {
+		t.Errorf("expected [a bb ccc], got %v", values)
+	}
+}
+
+func TestTakeFirstStep_TakeLastStep(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []int
+		n     int
+		first []int
+		last  []int
+	}{
+		{name: "nil slice", input: nil, n: 2, first: []int{}, last: []int{}},
+		{name: "empty slice", input: []int{}, n: 2, first: []int{}, last: []int{}},
+		{name: "single element", input: []int{7}, n: 2, first: []int{7}, last: []int{7}},
+		{name: "shorter than n", input: []int{1, 2}, n: 3, first: []int{1, 2}, last: []int{1, 2}},
+		{name: "longer than n", input: []int{1, 2, 3, 4}, n: 2, first: []int{1, 2}, last: []int{3, 4}},
+		{name: "negative n", input: []int{1, 2}, n: -1, first: []int{}, last: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := kyro.TakeFirstStep[int](tt.n)(tt.input, nil)
+			if err != nil {
+				t.Errorf("TakeFirstStep: unexpected error: %v", err)
+			}
+			if got := first.([]int); !slices.Equal(got, tt.first) {
+				t.Errorf("TakeFirstStep: expected %v, got %v", tt.first, got)
+			}
+
+			last, err := kyro.TakeLastStep[int](tt.n)(tt.input, nil)
+			if err != nil {
+				t.Errorf("TakeLastStep: unexpected error: %v", err)
+			}
+			if got := last.([]int); !slices.Equal(got, tt.last) {
+				t.Errorf("TakeLastStep: expected %v, got %v", tt.last, got)
+			}
+		})
+	}
+}
+
+func TestExecuteWithContext_Success(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.InParallel(kyro.AsPipelineStep(addOneStep), kyro.AsPipelineStep(multiplyByTwoStep)),
+	)
+
+	output, err := kyro.ExecuteWithContext(context.Background(), p)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{11, 20}) {
+		t.Errorf("expected output [11 20], got %v", output)
+	}
+}
+
+func TestExecuteWithContext_CancelAbortsInParallel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stepExited := make(chan struct{})
+
+	waitForCancel := kyro.AsPipelineStepWithContext(func(ctx context.Context, input int, err error) (int, error) {
+		defer close(stepExited)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	nextStepCalled := false
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.InParallel(kyro.AsPipelineStep(addOneStep), waitForCancel),
+		func(input any, err error) (any, error) {
+			nextStepCalled = true
+			return input, err
+		},
+	)
+
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	output, err := kyro.ExecuteWithContext(ctx, p)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+	if output != nil {
+		t.Errorf("expected nil output, got %v", output)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected prompt return after cancellation, took %v", time.Since(start))
+	}
+
+	select {
+	case <-stepExited:
+	case <-time.After(time.Second):
+		t.Error("expected the context aware step to exit after cancellation")
+	}
+
+	if nextStepCalled {
+		t.Error("expected no further step to run after cancellation")
+	}
+}
+
+func TestExecuteWithContext_AlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	_, err := kyro.ExecuteWithContext(ctx, func(input any, err error) (any, error) {
+		called = true
+		return nil, nil
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+	if called {
+		t.Error("expected pipeline not to run")
+	}
+}
+
+func TestExecuteWithContext_RawStepsReceivePlainInput(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		func(input any, err error) (any, error) {
+			value, ok := input.(int)
+			if !ok {
+				return nil, fmt.Errorf("expected int input, got %T", input)
+			}
+			return value * 3, err
+		},
+		kyro.InParallel(func(input any, err error) (any, error) {
+			return []any{input}, err
+		}),
+	)
+
+	output, err := kyro.ExecuteWithContext(context.Background(), p)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{[]any{30}}) {
+		t.Errorf("expected output [[30]], got %#v", output)
+	}
+}
+
+func TestFromContextStep_ReceivesContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	step := kyro.FromContextStep(func(ctx context.Context, input any, err error) (any, error) {
+		return []any{ctx.Value(key{}), input}, err
+	})
+
+	output, err := kyro.ExecuteWithContext(ctx, kyro.InSequence(kyro.AsPipelineGenerator(intGenerator), step))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{"value", 10}) {
+		t.Errorf("expected output [value 10], got %#v", output)
+	}
+
+	output, err = step(5, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{nil, 5}) {
+		t.Errorf("expected output [<nil> 5] without a context, got %#v", output)
+	}
+}
+
+func TestRetryStep_SucceedsAfterFailures(t *testing.T) {
+	calls := 0
+	flaky := kyro.AsPipelineStep(func(input int, err error) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("transient error")
+		}
+		return input * 2, nil
+	})
+
+	output, err := kyro.RetryStep(flaky, 5, time.Millisecond)(21, nil)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 42 {
+		t.Errorf("expected output 42, got %v", output)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryStep_PassThroughErrorNotRetried(t *testing.T) {
+	generatorErr := errors.New("generator failed")
+	calls := 0
+	passthrough := kyro.AsPipelineStep(func(input int, err error) (int, error) {
+		calls++
+		return input, err
+	})
+
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(func() (int, error) { return 0, generatorErr }),
+		kyro.RetryStep(passthrough, 3, time.Hour),
+	)
+
+	_, err := kyro.Execute(p)
+
+	if !errors.Is(err, generatorErr) {
+		t.Errorf("expected the generator error, got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected a single call, got %d", calls)
+	}
+}
+
+func TestRetryStep_ReturnsLastError(t *testing.T) {
+	calls := 0
+	failing := func(input any, err error) (any, error) {
+		calls++
+		return nil, fmt.Errorf("failure %d", calls)
+	}
+
+	_, err := kyro.RetryStep(failing, 3, time.Millisecond)(nil, nil)
+
+	if err == nil || err.Error() != "failure 3" {
+		t.Errorf("expected error 'failure 3', got: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestRetryStepExp_BackoffGrows(t *testing.T) {
+	var timestamps []time.Time
+	failing := func(input any, err error) (any, error) {
+		timestamps = append(timestamps, time.Now())
+		return nil, errors.New("failure")
+	}
+
+	_, err := kyro.RetryStepExp(failing, 3, 20*time.Millisecond)(nil, nil)
+
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if len(timestamps) != 3 {
+		t.Fatalf("expected 3 calls, got %d", len(timestamps))
+	}
+	if gap := timestamps[2].Sub(timestamps[1]); gap < 40*time.Millisecond {
+		t.Errorf("expected second backoff of at least 40ms, got %v", gap)
+	}
+}
+
+func TestTimeoutStep_FinishesInTime(t *testing.T) {
+	output, err := kyro.TimeoutStep(kyro.AsPipelineStep(addOneStep), time.Second)(1, nil)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 2 {
+		t.Errorf("expected output 2, got %v", output)
+	}
+}
+
+func TestTimeoutStep_TimesOut(t *testing.T) {
+	finished := make(chan struct{})
+	slow := func(input any, err error) (any, error) {
+		defer close(finished)
+		time.Sleep(100 * time.Millisecond)
+		return "too late", nil
+	}
+
+	start := time.Now()
+	output, err := kyro.TimeoutStep(slow, 10*time.Millisecond)(nil, nil)
+
+	if !errors.Is(err, kyro.ErrStepTimeout) {
+		t.Errorf("expected ErrStepTimeout, got: %v", err)
+	}
+	if err != nil && err.Error() != "step timed out after 10ms" {
+		t.Errorf("expected error 'step timed out after 10ms', got: %v", err)
+	}
+	if output != nil {
+		t.Errorf("expected nil output, got %v", output)
+	}
+	if time.Since(start) > 50*time.Millisecond {
+		t.Errorf("expected TimeoutStep not to block, took %v", time.Since(start))
+	}
+
+	// The late result must be discarded without panicking.
+	<-finished
+}
+
+func TestTimeoutStep_CancelsStepContext(t *testing.T) {
+	cancelled := make(chan struct{})
+	step := kyro.AsPipelineStepWithContext(func(ctx context.Context, input any, err error) (any, error) {
+		<-ctx.Done()
+		close(cancelled)
+		return nil, ctx.Err()
+	})
+
+	_, err := kyro.ExecuteWithContext(context.Background(), kyro.TimeoutStep(step, 10*time.Millisecond))
+
+	if !errors.Is(err, kyro.ErrStepTimeout) {
+		t.Errorf("expected ErrStepTimeout, got: %v", err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the step context to be cancelled on timeout")
+	}
+}
+
+func TestBranchStep_RoutesBothWays(t *testing.T) {
+	var trueCalls, falseCalls int
+	ifTrue := kyro.AsPipelineStep(func(input int, err error) (string, error) {
+		trueCalls++
+		return "even", err
+	})
+	ifFalse := kyro.AsPipelineStep(func(input int, err error) (string, error) {
+		falseCalls++
+		return "odd", err
+	})
+
+	branch := kyro.BranchStep(func(input any) bool {
+		return kyro.AssertIn[int](input)%2 == 0
+	}, ifTrue, ifFalse)
+
+	output, err := branch(4, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != "even" {
+		t.Errorf("expected output 'even', got %v", output)
+	}
+	if trueCalls != 1 || falseCalls != 0 {
+		t.Errorf("expected only the true branch to run, got true=%d false=%d", trueCalls, falseCalls)
+	}
+
+	output, err = kyro.ExecuteWithContext(context.Background(), kyro.InSequence(
+		kyro.AsPipelineGenerator(func() (int, error) { return 3, nil }),
+		branch,
+	))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != "odd" {
+		t.Errorf("expected output 'odd', got %v", output)
+	}
+	if trueCalls != 1 || falseCalls != 1 {
+		t.Errorf("expected only the false branch to run, got true=%d false=%d", trueCalls, falseCalls)
+	}
+}
+
+func TestInParallelCollect_JoinsAllErrors(t *testing.T) {
+	errFirst := errors.New("first failure")
+	errSecond := errors.New("second failure")
+
+	parallel := kyro.InParallelCollect(
+		func(input any, err error) (any, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, errFirst
+		},
+		kyro.AsPipelineStep(addOneStep),
+		func(input any, err error) (any, error) {
+			return nil, errSecond
+		},
+	)
+
+	output, err := parallel(10, nil)
+
+	if !errors.Is(err, errFirst) {
+		t.Errorf("expected joined error to contain %v, got: %v", errFirst, err)
+	}
+	if !errors.Is(err, errSecond) {
+		t.Errorf("expected joined error to contain %v, got: %v", errSecond, err)
+	}
+	if !reflect.DeepEqual(output, []any{nil, 11, nil}) {
+		t.Errorf("expected output [<nil> 11 <nil>], got %v", output)
+	}
+}
+
+func TestInParallelCollect_Success(t *testing.T) {
+	parallel := kyro.InParallelCollect(kyro.AsPipelineStep(addOneStep), kyro.AsPipelineStep(multiplyByTwoStep))
+
+	output, err := parallel(10, nil)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{11, 20}) {
+		t.Errorf("expected output [11 20], got %v", output)
+	}
+}
+
+func TestInParallelLimited_QueuesSteps(t *testing.T) {
+	var running, maxRunning int
+	var mu sync.Mutex
+
+	steps := make([]kyro.PipelineStep, 6)
+	for i := range steps {
+		steps[i] = kyro.AsPipelineStep(func(input int, err error) (int, error) {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			time.Sleep(30 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return input + i, nil
+		})
+	}
+
+	start := time.Now()
+	output, err := kyro.InParallelLimited(2, steps...)(10, nil)
+	duration := time.Since(start)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{10, 11, 12, 13, 14, 15}) {
+		t.Errorf("expected ordered results, got %v", output)
+	}
+	if maxRunning > 2 {
+		t.Errorf("expected at most 2 steps running at once, got %d", maxRunning)
+	}
+	if duration < 90*time.Millisecond {
+		t.Errorf("expected steps to queue up in 3 rounds of 30ms, took %v", duration)
+	}
+}
+
+func TestInParallelLimited_Unbounded(t *testing.T) {
+	steps := make([]kyro.PipelineStep, 5)
+	for i := range steps {
+		steps[i] = sleepAndReturnIntStep(i, 30*time.Millisecond)
+	}
+
+	start := time.Now()
+	output, err := kyro.InParallelLimited(0, steps...)(nil, nil)
+	duration := time.Since(start)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{0, 1, 2, 3, 4}) {
+		t.Errorf("expected ordered results, got %v", output)
+	}
+	if duration > 80*time.Millisecond {
+		t.Errorf("expected all steps to run at once, took %v", duration)
+	}
+}
+
+func TestInParallelLimited_StopsLaunchingOnError(t *testing.T) {
+	var launched int
+	var mu sync.Mutex
+
+	steps := []kyro.PipelineStep{
+		func(input any, err error) (any, error) {
+			return nil, errors.New("first step failed")
+		},
+	}
+	for range 5 {
+		steps = append(steps, func(input any, err error) (any, error) {
+			mu.Lock()
+			launched++
+			mu.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			return nil, nil
+		})
+	}
+
+	_, err := kyro.InParallelLimited(1, steps...)(nil, nil)
+	time.Sleep(50 * time.Millisecond)
+
+	if err == nil || err.Error() != "first step failed" {
+		t.Errorf("expected error 'first step failed', got: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if launched > 1 {
+		t.Errorf("expected no further steps to be launched after the error, got %d", launched)
+	}
+}
+
+func TestMapStep(t *testing.T) {
+	toString := kyro.MapStep(func(i int) (string, error) {
+		if i < 0 {
+			return "", fmt.Errorf("negative value %d", i)
+		}
+		return fmt.Sprintf("#%d", i), nil
+	})
+
+	output, err := toString([]int{1, 2, 3}, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []string{"#1", "#2", "#3"}) {
+		t.Errorf("expected [#1 #2 #3], got %v", output)
+	}
+
+	output, err = toString([]int{1, -2, -3}, nil)
+	if err == nil || err.Error() != "negative value -2" {
+		t.Errorf("expected error 'negative value -2', got: %v", err)
+	}
+	if result := kyro.AssertIn[[]string](output); result != nil {
+		t.Errorf("expected nil output on error, got %v", result)
+	}
+
+	for _, input := range []any{nil, []int{}} {
+		output, err = toString(input, nil)
+		if err != nil {
+			t.Errorf("unexpected error for input %#v: %v", input, err)
+		}
+		if result := kyro.AssertIn[[]string](output); result == nil || len(result) != 0 {
+			t.Errorf("expected empty slice for input %#v, got %#v", input, output)
+		}
+	}
+}
+
+func TestRecoverStep_ConvertsPanicToError(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(func() (string, error) { return "not an int", nil }),
+		kyro.RecoverStep(kyro.AsPipelineStep(addOneStep)),
+	)
+
+	output, err := kyro.Execute(p)
+
+	var panicErr *kyro.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected *kyro.PanicError, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "panic in step: expected type int, got string") {
+		t.Errorf("expected panic message in error, got: %v", err)
+	}
+	if !strings.Contains(string(panicErr.Stack), "TestRecoverStep_ConvertsPanicToError") {
+		t.Errorf("expected stack trace to contain the test function, got: %s", panicErr.Stack)
+	}
+	if output != nil {
+		t.Errorf("expected nil output, got %v", output)
+	}
+}
+
+func TestRecoverStep_RecoversParallelPanic(t *testing.T) {
+	p := kyro.RecoverStep(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.InParallel(
+			kyro.AsPipelineStep(addOneStep),
+			kyro.AsPipelineStep(func(input int, err error) (int, error) {
+				panic("boom")
+			}),
+		),
+	))
+
+	output, err := kyro.Execute(p)
+
+	var panicErr *kyro.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("expected *kyro.PanicError, got: %v", err)
+	}
+	if panicErr.Value != "boom" {
+		t.Errorf("expected panic value 'boom', got %v", panicErr.Value)
+	}
+	if !strings.Contains(string(panicErr.Stack), "TestRecoverStep_RecoversParallelPanic") {
+		t.Errorf("expected stack trace of the panicking step, got: %s", panicErr.Stack)
+	}
+	if output != nil {
+		t.Errorf("expected nil output, got %v", output)
+	}
+}
+
+func TestRecoverStep_PassesThroughWithoutPanic(t *testing.T) {
+	output, err := kyro.RecoverStep(kyro.AsPipelineStep(addOneStep))(1, nil)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 2 {
+		t.Errorf("expected output 2, got %v", output)
+	}
+}
+
+func TestTapStep_PassesValueThrough(t *testing.T) {
+	input := ComplexType{Number: 1, Slice: []string{"a"}}
+	var tapped any
+
+	output, err := kyro.TapStep(func(input any, err error) {
+		tapped = input
+	})(input, nil)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, input) || !reflect.DeepEqual(tapped, input) {
+		t.Errorf("expected value %v before and after the tap, got tapped=%v output=%v", input, tapped, output)
+	}
+}
+
+func TestTapStep_KeepsErrorAndNil(t *testing.T) {
+	inFlight := errors.New("in-flight error")
+	var tappedErr error
+
+	output, err := kyro.TapStep(func(input any, err error) {
+		tappedErr = err
+	})(nil, inFlight)
+
+	if err != inFlight || tappedErr != inFlight {
+		t.Errorf("expected error to be passed through, got tapped=%v returned=%v", tappedErr, err)
+	}
+	if output != nil {
+		t.Errorf("expected nil output, got %v", output)
+	}
+}
+
+func TestInParallelPartial_KeepsCompletedResults(t *testing.T) {
+	parallel := kyro.InParallelPartial(
+		kyro.AsPipelineStep(addOneStep),
+		func(input any, err error) (any, error) {
+			time.Sleep(20 * time.Millisecond)
+			return nil, errors.New("parallel error")
+		},
+		sleepAndReturnIntStep(3, time.Second),
+		kyro.AsPipelineStep(multiplyByTwoStep),
+	)
+
+	output, err := parallel(10, nil)
+
+	if err == nil || err.Error() != "parallel error" {
+		t.Errorf("expected error 'parallel error', got: %v", err)
+	}
+
+	results, ok := output.([]any)
+	if !ok {
+		t.Fatalf("expected output to be []any, got %T", output)
+	}
+	if len(results) != 4 {
+		t.Fatalf("expected 4 result slots, got %d", len(results))
+	}
+	if !reflect.DeepEqual(results, []any{11, nil, nil, 20}) {
+		t.Errorf("expected [11 <nil> <nil> 20], got %v", results)
+	}
+}
+
+func TestInParallelPartial_Success(t *testing.T) {
+	output, err := kyro.InParallelPartial(kyro.AsPipelineStep(addOneStep))(1, nil)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{2}) {
+		t.Errorf("expected [2], got %v", output)
+	}
+}
+
+func TestRepeatStep(t *testing.T) {
+	increment := kyro.AsPipelineStep(addOneStep)
+
+	output, err := kyro.RepeatStep(increment, 5)(10, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 15 {
+		t.Errorf("expected output 15, got %v", output)
+	}
+
+	output, err = kyro.RepeatStep(increment, 0)(10, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 10 {
+		t.Errorf("expected output 10 for zero repetitions, got %v", output)
+	}
+}
+
+func TestRepeatStep_StopsOnError(t *testing.T) {
+	calls := 0
+	failOnThird := kyro.AsPipelineStep(func(input int, err error) (int, error) {
+		calls++
+		if calls == 3 {
+			return input, errors.New("third iteration failed")
+		}
+		return input + 1, nil
+	})
+
+	output, err := kyro.RepeatStep(failOnThird, 5)(0, nil)
+
+	if err == nil || err.Error() != "third iteration failed" {
+		t.Errorf("expected error 'third iteration failed', got: %v", err)
+	}
+	if output != 2 {
+		t.Errorf("expected output 2, got %v", output)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+type notFoundError struct {
+	ID int
+}
+
+func (e *notFoundError) Error() string {
+	return fmt.Sprintf("record %d not found", e.ID)
+}
+
+func TestValidate_ReportsMismatchedStep(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.NamedStep("add", kyro.AsPipelineStep(addOneStep)),
+		kyro.NamedStep("format", kyro.AsPipelineStep(intToStringStep)),
+		// The string produced by "format" does not fit the int expected by "double".
+		kyro.NamedStep("double", kyro.AsPipelineStep(multiplyByTwoStep)),
+	)
+
+	err := kyro.Validate(p, 1)
+
+	var validationErr *kyro.ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected *ValidationError, got %v", err)
+	}
+	if validationErr.Step != "double" {
+		t.Errorf("expected mismatch in step 'double', got %q", validationErr.Step)
+	}
+	if validationErr.Value != "expected type int, got string" {
+		t.Errorf("unexpected panic value: %v", validationErr.Value)
+	}
+}
+
+func TestValidate_CompatibleSteps(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.NamedStep("add", kyro.AsPipelineStep(addOneStep)),
+		kyro.NamedStep("format", kyro.AsPipelineStep(intToStringStep)),
+	)
+
+	if err := kyro.Validate(p, 1); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := kyro.Validate(kyro.AsPipelineStep(addOneStep), "one"); err == nil || err.Error() != "pipeline panicked: expected type int, got string" {
+		t.Errorf("expected unnamed mismatch, got %v", err)
+	}
+}
+
+func TestValidate_RawSteps(t *testing.T) {
+	increment := func(input any, _ error) (any, error) {
+		return input.(int) + 1, nil
+	}
+
+	if err := kyro.Validate(kyro.InSequence(increment), 3); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	err := kyro.Validate(kyro.InSequence(kyro.NamedStep("increment", increment)), "three")
+
+	var validationErr *kyro.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Step != "increment" {
+		t.Errorf("expected mismatch in step 'increment', got %v", err)
+	}
+}
+
+func TestValidate_ParallelSteps(t *testing.T) {
+	tests := []struct {
+		name     string
+		parallel func(steps ...kyro.PipelineStep) kyro.PipelineStep
+	}{
+		{name: "InParallel", parallel: kyro.InParallel},
+		{name: "InParallelCollect", parallel: kyro.InParallelCollect},
+		{name: "InParallelQuorum", parallel: func(steps ...kyro.PipelineStep) kyro.PipelineStep {
+			return kyro.InParallelQuorum(len(steps), steps...)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := kyro.InSequence(
+				kyro.NamedStep("format", kyro.AsPipelineStep(intToStringStep)),
+				tt.parallel(
+					kyro.NamedStep("echo", kyro.AsPipelineStep(func(input string, err error) (string, error) {
+						return input, err
+					})),
+					// The string produced by "format" does not fit the int expected by "double".
+					kyro.NamedStep("double", kyro.AsPipelineStep(multiplyByTwoStep)),
+				),
+			)
+
+			err := kyro.Validate(p, 1)
+
+			var validationErr *kyro.ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected *ValidationError, got %v", err)
+			}
+			if validationErr.Step != "double" {
+				t.Errorf("expected mismatch in step 'double', got %q", validationErr.Step)
+			}
+			if validationErr.Value != "expected type int, got string" {
+				t.Errorf("unexpected panic value: %v", validationErr.Value)
+			}
+		})
+	}
+}
+
+func TestNamedStep_WrapsError(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.NamedStep("double", kyro.AsPipelineStep(multiplyByTwoStep)),
+		kyro.NamedStep("lookup", kyro.AsPipelineStep(func(input int, err error) (int, error) {
+			return 0, &notFoundError{ID: input}
+		})),
+		kyro.NamedStep("passthrough", func(input any, err error) (any, error) {
+			return input, err
+		}),
+	)
+
+	_, err := kyro.Execute(p)
+
+	if err == nil || err.Error() != `step "lookup": record 20 not found` {
+		t.Errorf("expected error 'step \"lookup\": record 20 not found', got: %v", err)
+	}
+
+	var notFound *notFoundError
+	if !errors.As(err, &notFound) || notFound.ID != 20 {
+		t.Errorf("expected to unwrap *notFoundError with ID 20, got: %v", err)
+	}
+}
+
+func TestNamedStep_IsTraced(t *testing.T) {
+	_, traces, err := kyro.ExecuteTraced(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.NamedStep("add", kyro.AsPipelineStep(addOneStep)),
+	))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(traces) != 1 || traces[0].Name != "add" {
+		t.Errorf("expected a single trace for 'add', got %+v", traces)
+	}
+}
+
+func TestExecuteWithHooks_FiresAroundEveryStep(t *testing.T) {
+	var before, after []int
+	var outputs []any
+
+	hooks := kyro.Hooks{
+		BeforeStep: func(index int) {
+			before = append(before, index)
+		},
+		AfterStep: func(index int, output any, err error, duration time.Duration) {
+			after = append(after, index)
+			outputs = append(outputs, output)
+			if duration < 0 {
+				t.Errorf("expected non-negative duration, got %v", duration)
+			}
+		},
+	}
+
+	output, err := kyro.ExecuteWithHooks(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.AsPipelineStep(addOneStep),
+		kyro.AsPipelineStep(multiplyByTwoStep),
+	), hooks)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 22 {
+		t.Errorf("expected output 22, got %v", output)
+	}
+	if !reflect.DeepEqual(before, []int{0, 1, 2}) {
+		t.Errorf("expected BeforeStep for [0 1 2], got %v", before)
+	}
+	if !reflect.DeepEqual(after, []int{0, 1, 2}) {
+		t.Errorf("expected AfterStep for [0 1 2], got %v", after)
+	}
+	if !reflect.DeepEqual(outputs, []any{10, 11, 22}) {
+		t.Errorf("expected outputs [10 11 22], got %v", outputs)
+	}
+}
+
+func TestExecuteWithHooks_NilCallbacks(t *testing.T) {
+	output, err := kyro.ExecuteWithHooks(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.AsPipelineStep(addOneStep),
+	), kyro.Hooks{})
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 11 {
+		t.Errorf("expected output 11, got %v", output)
+	}
+}
+
+func TestExecuteWithHooks_RawStepsReceivePlainInput(t *testing.T) {
+	raw := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		func(input any, err error) (any, error) {
+			return input.(int) + 1, err
+		},
+	)
+
+	calls := 0
+	for _, hooks := range []kyro.Hooks{{}, {BeforeStep: func(int) { calls++ }}} {
+		output, err := kyro.ExecuteWithHooks(raw, hooks)
+
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if output != 11 {
+			t.Errorf("expected output 11, got %v", output)
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("expected BeforeStep to be called twice, got %d", calls)
+	}
+}
+
+func TestCollectStep_TypedArguments(t *testing.T) {
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.InParallel(
+			kyro.AsPipelineStep(intToStringStep),
+			kyro.AsPipelineStep(addOneStep),
+		),
+		kyro.CollectStep(func(s string, n int) (any, error) {
+			return fmt.Sprintf("%s:%d", s, n), nil
+		}),
+	)
+
+	output, err := kyro.Execute(p)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != "10:11" {
+		t.Errorf("expected output '10:11', got %v", output)
+	}
+}
+
+func TestCollectStep3_And4(t *testing.T) {
+	sum3 := kyro.CollectStep3(func(a, b, c int) (any, error) {
+		return a + b + c, nil
+	})
+	output, err := sum3([]any{1, 2, 3}, nil)
+	if err != nil || output != 6 {
+		t.Errorf("expected output 6, got %v (err: %v)", output, err)
+	}
+
+	join4 := kyro.CollectStep4(func(a string, b int, c bool, d []string) (any, error) {
+		return fmt.Sprint(a, b, c, d), nil
+	})
+	output, err = join4([]any{"a", 1, true, []string{"x"}}, nil)
+	if err != nil || output != "a1 true [x]" {
+		t.Errorf("expected output 'a1 true [x]', got %v (err: %v)", output, err)
+	}
+}
+
+func TestCollectStep_ArityAndTypeErrors(t *testing.T) {
+	called := false
+	collect := kyro.CollectStep(func(a int, b int) (any, error) {
+		called = true
+		return a + b, nil
+	})
+
+	for _, input := range []any{nil, []any{1}, []any{1, 2, 3}, "not a slice", []any{1, "two"}} {
+		output, err := collect(input, nil)
+		if err == nil {
+			t.Errorf("expected error for input %#v, got nil", input)
+		}
+		if output != nil {
+			t.Errorf("expected nil output for input %#v, got %v", input, output)
+		}
+	}
+
+	if called {
+		t.Error("expected fn not to be called for invalid input")
+	}
+}
+
+func TestDecodeStep(t *testing.T) {
+	// decodeKeyValue decodes records of the form "key=value" into a pair.
+	decodeKeyValue := kyro.DecodeStep(func(record []byte) ([2]string, error) {
+		key, value, ok := strings.Cut(string(record), "=")
+		if !ok {
+			return [2]string{}, fmt.Errorf("missing '=' in %q", record)
+		}
+		return [2]string{key, value}, nil
+	})
+
+	output, err := decodeKeyValue([][]byte{[]byte("a=1"), []byte("b=2")}, nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, [][2]string{{"a", "1"}, {"b", "2"}}) {
+		t.Errorf("expected [[a 1] [b 2]], got %v", output)
+	}
+
+	output, err = decodeKeyValue([]byte("c=3"), nil)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != [2]string{"c", "3"} {
+		t.Errorf("expected [c 3], got %v", output)
+	}
+
+	output, err = decodeKeyValue([][]byte{[]byte("a=1"), []byte("broken")}, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to decode record 1: missing '='") {
+		t.Errorf("expected decode error for record 1, got: %v", err)
+	}
+	if output != nil {
+		t.Errorf("expected nil output on error, got %v", output)
+	}
+}
+
+func TestInParallelQuorum_ReturnsAfterQuorum(t *testing.T) {
+	var cancelled atomic.Int32
+	slowStep := kyro.AsPipelineStepWithContext(func(ctx context.Context, input int, err error) (int, error) {
+		select {
+		case <-time.After(time.Second):
+			return 0, nil
+		case <-ctx.Done():
+			cancelled.Add(1)
+			return 0, ctx.Err()
+		}
+	})
+
+	pipeline := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.InParallelQuorum(3,
+			slowStep,
+			sleepAndReturnIntStep(1, 10*time.Millisecond),
+			sleepAndReturnIntStep(2, 20*time.Millisecond),
+			slowStep,
+			sleepAndReturnIntStep(3, 30*time.Millisecond),
+		),
+	)
+
+	start := time.Now()
+	output, err := kyro.ExecuteWithContext(context.Background(), pipeline)
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(output, []any{1, 2, 3}) {
+		t.Errorf("expected the 3 fastest results [1 2 3], got %v", output)
+	}
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("expected to return after the quorum was reached, took %v", elapsed)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for cancelled.Load() != 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := cancelled.Load(); n != 2 {
+		t.Errorf("expected the 2 outstanding steps to be cancelled, got %d", n)
+	}
+}
+
+func TestInParallelQuorum_TooManyFailures(t *testing.T) {
+	errA := errors.New("replica a down")
+	errB := errors.New("replica b down")
+	errC := errors.New("replica c down")
+	failing := func(stepErr error) kyro.PipelineStep {
+		return func(input any, err error) (any, error) {
+			return nil, stepErr
+		}
+	}
+
+	output, err := kyro.InParallelQuorum(3,
+		failing(errA),
+		kyro.AsPipelineStep(addOneStep),
+		failing(errB),
+		kyro.AsPipelineStep(addOneStep),
+		failing(errC),
+	)(1, nil)
+
+	if !errors.Is(err, errA) || !errors.Is(err, errB) || !errors.Is(err, errC) {
+		t.Errorf("expected all failures to be joined, got: %v", err)
+	}
+	if output != nil {
+		t.Errorf("expected nil output, got %v", output)
+	}
+}
+
+func TestEnrichStep(t *testing.T) {
+	type order struct {
+		CustomerID int
+		Customer   string
+	}
+
+	var lookups [][]int
+	enrich := kyro.EnrichStep(
+		func(o order) int { return o.CustomerID },
+		func(ids []int) (map[int]string, error) {
+			lookups = append(lookups, ids)
+			return map[int]string{1: "alice", 2: "bob"}, nil
+		},
+		func(o order, name string) order {
+			o.Customer = name
+			return o
+		},
+	)
+
+	output, err := enrich([]order{{CustomerID: 1}, {CustomerID: 2}, {CustomerID: 1}, {CustomerID: 3}}, nil)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []order{{1, "alice"}, {2, "bob"}, {1, "alice"}, {3, ""}}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("expected %v, got %v", expected, output)
+	}
+	if !reflect.DeepEqual(lookups, [][]int{{1, 2, 3}}) {
+		t.Errorf("expected a single lookup of the distinct keys, got %v", lookups)
+	}
+
+	failing := kyro.EnrichStep(
+		func(o order) int { return o.CustomerID },
+		func(ids []int) (map[int]string, error) { return nil, errors.New("lookup failed") },
+		func(o order, name string) order { return o },
+	)
+
+	output, err = failing([]order{{CustomerID: 1}}, nil)
+	if err == nil || err.Error() != "lookup failed" {
+		t.Errorf("expected error 'lookup failed', got: %v", err)
+	}
+	if result := kyro.AssertIn[[]order](output); result != nil {
+		t.Errorf("expected nil output on error, got %v", result)
+	}
+}
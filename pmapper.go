@@ -0,0 +1,138 @@
+package kyro
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ParallelMapper represents a queue for mapping items to results in parallel. It is built
+// on a ParallelQueue and shares its worker pool, progress and error reporting, but
+// additionally collects the results of the successfully processed items.
+type ParallelMapper[ITEM any, RESULT any] struct {
+	queue   *ParallelQueue[ITEM]
+	mapFunc func(ITEM) (RESULT, error)
+	ordered bool
+}
+
+// NewParallelMapper creates a new ParallelMapper with the specified number of workers.
+func NewParallelMapper[ITEM any, RESULT any](numberOfWorkers int) *ParallelMapper[ITEM, RESULT] {
+	return &ParallelMapper[ITEM, RESULT]{
+		queue: NewParallelQueue[ITEM](numberOfWorkers),
+	}
+}
+
+// WithItems sets the items to be mapped.
+func (m *ParallelMapper[ITEM, RESULT]) WithItems(items *[]ITEM) *ParallelMapper[ITEM, RESULT] {
+	m.queue.WithItems(items)
+	return m
+}
+
+// OnMapItem sets the function to be used for mapping each item to its result.
+func (m *ParallelMapper[ITEM, RESULT]) OnMapItem(mapFunc func(ITEM) (RESULT, error)) *ParallelMapper[ITEM, RESULT] {
+	m.mapFunc = mapFunc
+	return m
+}
+
+// WithOrderedResults makes Process return the results in the order of their items instead
+// of the order in which they completed. The results are buffered and sorted once all items
+// are processed. The results of errored items are left out, so the result at a given
+// position does not necessarily belong to the item at the same position.
+func (m *ParallelMapper[ITEM, RESULT]) WithOrderedResults() *ParallelMapper[ITEM, RESULT] {
+	m.ordered = true
+	return m
+}
+
+// WithProgressNotifier sets the progress notification function and the batch size.
+// batch is the number of items processed before the progress function is called.
+func (m *ParallelMapper[ITEM, RESULT]) WithProgressNotifier(batch int, progressFunc ProgressNotifier) *ParallelMapper[ITEM, RESULT] {
+	m.queue.WithProgressNotifier(batch, progressFunc)
+	return m
+}
+
+// WithErrorNotifier sets the error notification function.
+// errorFunc is the function to call when an error occurs during mapping.
+func (m *ParallelMapper[ITEM, RESULT]) WithErrorNotifier(errorFunc ErrorNotifier[ITEM]) *ParallelMapper[ITEM, RESULT] {
+	m.queue.WithErrorNotifier(errorFunc)
+	return m
+}
+
+// WithContext sets a context to stop the mapping early, see ParallelQueue.WithContext.
+func (m *ParallelMapper[ITEM, RESULT]) WithContext(ctx context.Context) *ParallelMapper[ITEM, RESULT] {
+	m.queue.WithContext(ctx)
+	return m
+}
+
+// Queue returns the underlying ParallelQueue to configure options that are not mirrored
+// by the mapper. Its process function is replaced by the mapping when Process is called.
+func (m *ParallelMapper[ITEM, RESULT]) Queue() *ParallelQueue[ITEM] {
+	return m.queue
+}
+
+// Process starts the parallel mapping of the items. It returns the results of all items
+// that were mapped successfully, in no particular order unless WithOrderedResults is set,
+// the items that failed to map, and an error if any critical error occurred during setup
+// or processing.
+func (m *ParallelMapper[ITEM, RESULT]) Process() (*[]RESULT, *[]ITEM, error) {
+	return m.process(m.queue.Process)
+}
+
+// ProcessWithin works like Process, but stops the mapping once d has passed and returns the
+// results mapped so far together with the items that were not mapped by then, see
+// ParallelQueue.ProcessWithin.
+func (m *ParallelMapper[ITEM, RESULT]) ProcessWithin(d time.Duration) (*[]RESULT, *[]ITEM, *[]ITEM, error) {
+	var unprocessedItems *[]ITEM
+
+	results, erroredItems, err := m.process(func() (*[]ITEM, error) {
+		erroredItems, unprocessed, err := m.queue.ProcessWithin(d)
+		unprocessedItems = unprocessed
+		return erroredItems, err
+	})
+
+	return results, erroredItems, unprocessedItems, err
+}
+
+// process maps the items by running the queue with run and collects the results.
+func (m *ParallelMapper[ITEM, RESULT]) process(run func() (*[]ITEM, error)) (*[]RESULT, *[]ITEM, error) {
+	type indexedResult struct {
+		index  int
+		result RESULT
+	}
+
+	var indexedResults []indexedResult
+	var resultsMutex sync.Mutex
+
+	m.queue.processFunc = nil
+	m.queue.batchFunc = nil
+	m.queue.indexedFunc = nil
+	if m.mapFunc != nil {
+		m.queue.indexedFunc = func(index int, item ITEM) error {
+			result, err := m.mapFunc(item)
+			if err != nil {
+				return err
+			}
+
+			resultsMutex.Lock()
+			indexedResults = append(indexedResults, indexedResult{index: index, result: result})
+			resultsMutex.Unlock()
+			return nil
+		}
+	}
+
+	erroredItems, err := run()
+
+	if m.ordered {
+		slices.SortFunc(indexedResults, func(a, b indexedResult) int {
+			return cmp.Compare(a.index, b.index)
+		})
+	}
+
+	var results []RESULT
+	for _, indexed := range indexedResults {
+		results = append(results, indexed.result)
+	}
+
+	return &results, erroredItems, err
+}
@@ -0,0 +1,113 @@
+package kyro_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestParallelMapper_Process_SumsResults(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i + 1
+	}
+
+	results, erroredItems, err := kyro.NewParallelMapper[int, int](4).
+		WithItems(&items).
+		OnMapItem(func(item int) (int, error) {
+			if item%25 == 0 {
+				return 0, errors.New("rejected")
+			}
+			return item * 2, nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if len(*erroredItems) != 4 {
+		t.Errorf("expected 4 errored items, got %v", *erroredItems)
+	}
+	if len(*results) != len(items)-len(*erroredItems) {
+		t.Errorf("expected %d results, got %d", len(items)-len(*erroredItems), len(*results))
+	}
+
+	sum := 0
+	for _, result := range *results {
+		sum += result
+	}
+	if expected := 2 * (5050 - 25 - 50 - 75 - 100); sum != expected {
+		t.Errorf("expected sum %d, got %d", expected, sum)
+	}
+}
+
+func TestParallelMapper_Process_NoMapFunc(t *testing.T) {
+	items := []int{1}
+
+	_, _, err := kyro.NewParallelMapper[int, string](1).WithItems(&items).Process()
+
+	if !errors.Is(err, kyro.ErrNoProcessFunc) {
+		t.Errorf("expected ErrNoProcessFunc, got: %v", err)
+	}
+}
+
+func TestParallelMapper_WithOrderedResults(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	results, _, err := kyro.NewParallelMapper[int, string](8).
+		WithItems(&items).
+		WithOrderedResults().
+		OnMapItem(func(item int) (string, error) {
+			// Later items finish first, which scrambles the completion order.
+			time.Sleep(time.Duration(len(items)-item) * time.Millisecond)
+			return strconv.Itoa(item), nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*results) != len(items) {
+		t.Fatalf("expected %d results, got %d", len(items), len(*results))
+	}
+	for i, result := range *results {
+		if result != strconv.Itoa(i) {
+			t.Fatalf("expected results in input order, got %v", *results)
+		}
+	}
+}
+
+func TestParallelMapper_ProcessWithin(t *testing.T) {
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+
+	results, erroredItems, unprocessedItems, err := kyro.NewParallelMapper[int, string](2).
+		WithItems(&items).
+		WithOrderedResults().
+		OnMapItem(func(item int) (string, error) {
+			time.Sleep(10 * time.Millisecond)
+			return strconv.Itoa(item), nil
+		}).
+		ProcessWithin(50 * time.Millisecond)
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*erroredItems) != 0 {
+		t.Errorf("expected no errored items, got %v", *erroredItems)
+	}
+	if len(*results) == 0 || len(*unprocessedItems) == 0 {
+		t.Fatalf("expected partial results, got %d results and %d unprocessed items", len(*results), len(*unprocessedItems))
+	}
+	if len(*results)+len(*unprocessedItems) != len(items) {
+		t.Errorf("expected %d results and unprocessed items, got %d and %d", len(items), len(*results), len(*unprocessedItems))
+	}
+}
@@ -1,24 +1,82 @@
 package kyro
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"iter"
+	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+var (
+	// ErrNoWorkers is returned by Process if the number of workers is not positive.
+	ErrNoWorkers = errors.New("number of workers must be positive")
+	// ErrNoItems is returned by Process if no items were set or the items are empty.
+	ErrNoItems = errors.New("items must be non-nil and non-empty")
+	// ErrNoProcessFunc is returned by Process if no process function was set.
+	ErrNoProcessFunc = errors.New("process function must be set")
+	// ErrItemPanic is wrapped by the error reported for an item whose processing panicked.
+	ErrItemPanic = errors.New("panic processing item")
+	// ErrMaxErrors is returned by Process if it stopped early because of WithMaxErrors.
+	ErrMaxErrors = errors.New("aborted after reaching max errors")
+
+	// errNotProcessed is returned by processWithRetries if the queue was cancelled while
+	// waiting for the rate limiter before the item was processed for the first time.
+	errNotProcessed = errors.New("item not processed")
+)
+
 // ParallelQueue represents a queue for processing items in parallel.
 type ParallelQueue[ITEM any] struct {
 	items           *[]ITEM
+	itemCh          <-chan ITEM
+	outputCh        chan<- ITEM
 	numberOfWorkers int
+	autoWorkers     bool
 
 	processFunc    ProcessFunc[ITEM]
+	indexedFunc    func(index int, item ITEM) error
+	batchFunc      func([]ITEM) error
+	batchSize      int
 	processed      int
 	processedMutex sync.Mutex
 
 	progressBatch int
 	progressFunc  ProgressNotifier
 
-	errorFunc ErrorNotifier[ITEM]
+	timedProgressInterval time.Duration
+	timedProgressFunc     ProgressNotifier
+
+	errorFunc      ErrorNotifier[ITEM]
+	assignmentFunc func(workerID int, item ITEM)
+
+	retryAttempts int
+	retryBackoff  time.Duration
+
+	maxErrors          int
+	maxErroredRetained int
+
+	rateLimiter *RateLimiter
+	inFlight    *InFlight
+
+	ctx context.Context
+
+	// unprocessed collects the items skipped after a cancellation during ProcessWithin.
+	unprocessed *unprocessedItems[ITEM]
+
+	stats ProcessStats
+
+	stopMutex sync.Mutex
+	stopped   bool
+	stopFunc  context.CancelFunc
+
+	shardIndex   int
+	shardCount   int
+	shardKeyFunc func(ITEM) uint64
 }
 
 // NewParallelQueue creates a new ParallelQueue with the specified number of workers.
@@ -29,15 +87,96 @@ func NewParallelQueue[ITEM any](numberOfWorkers int) *ParallelQueue[ITEM] {
 	}
 }
 
+// AutoWorkers returns a number of workers suited for processing itemCount items, which is
+// the number of CPUs, but never more than there are items. If the number of items is not
+// known, i.e. itemCount is not positive, it returns the number of CPUs.
+func AutoWorkers(itemCount int) int {
+	workers := runtime.NumCPU()
+	if itemCount > 0 {
+		workers = min(workers, itemCount)
+	}
+	return workers
+}
+
+// WithAutoWorkers makes the queue size its number of workers with AutoWorkers when Process
+// is called, overriding the number of workers passed to NewParallelQueue. This keeps small
+// inputs from starting many idle workers.
+func (c *ParallelQueue[ITEM]) WithAutoWorkers() *ParallelQueue[ITEM] {
+	c.autoWorkers = true
+	return c
+}
+
 // WithItems sets the items to be processed by the queue.
 func (c *ParallelQueue[ITEM]) WithItems(items *[]ITEM) *ParallelQueue[ITEM] {
 	c.items = items
+	c.itemCh = nil
+	return c
+}
+
+// WithItemChannel sets a channel to take the items to be processed from instead of a slice.
+// The queue hands the items to the workers as they arrive and Process returns once the
+// channel is closed and all items received are processed, so the items never have to be
+// held in memory at once. It replaces the items set with WithItems.
+func (c *ParallelQueue[ITEM]) WithItemChannel(items <-chan ITEM) *ParallelQueue[ITEM] {
+	c.itemCh = items
+	c.items = nil
+	return c
+}
+
+// WithOutputChannel makes the workers forward every successfully processed item to ch, so that
+// it can feed the item channel of another queue to chain processing stages. A worker blocks
+// until ch accepts the item, unless the queue is cancelled or stopped first. The items not
+// forwarded then are counted in ProcessStats.Unforwarded, and returned by ProcessWithin
+// alongside the unprocessed items, so that they can be handed on again. ch is closed when
+// Process returns, so the queue can only be processed once.
+func (c *ParallelQueue[ITEM]) WithOutputChannel(ch chan<- ITEM) *ParallelQueue[ITEM] {
+	c.outputCh = ch
 	return c
 }
 
 // OnProcessItem sets the function to be used for processing each item.
 func (c *ParallelQueue[ITEM]) OnProcessItem(processFunc ProcessFunc[ITEM]) *ParallelQueue[ITEM] {
 	c.processFunc = processFunc
+	c.batchFunc = nil
+	return c
+}
+
+// OnProcessItemInFlight sets a function to be used for processing each item that dispatches
+// asynchronous work, e.g. by starting goroutines, and returns before the work is done. The
+// function receives the InFlight limiting the concurrent operations, which it acquires for
+// every operation it dispatches. See WithMaxInFlight.
+func (c *ParallelQueue[ITEM]) OnProcessItemInFlight(processFunc func(item ITEM, inFlight *InFlight) error) *ParallelQueue[ITEM] {
+	return c.OnProcessItem(func(item ITEM) error {
+		return processFunc(item, c.inFlight)
+	})
+}
+
+// OnProcessBatch sets a function to be used for processing the items in batches of up to
+// batchSize items, e.g. for backends supporting bulk operations. Workers take a full batch
+// at a time, only the last batch of the input may be smaller. If a batch fails, all of its
+// items are reported as errored. Retries and rate limiting apply to whole batches.
+// It replaces the function set with OnProcessItem or OnProcessPipeline.
+func (c *ParallelQueue[ITEM]) OnProcessBatch(batchSize int, batchFunc func([]ITEM) error) *ParallelQueue[ITEM] {
+	c.batchFunc = batchFunc
+	c.batchSize = batchSize
+	c.processFunc = nil
+	return c
+}
+
+// OnProcessPipeline sets a pipeline to be run for each item, seeded with the item via
+// ExecuteWith. An item fails to process if the pipeline returns an error. A panic raised
+// by the pipeline, e.g. by AssertIn on a mis-wired step, is recovered like a panic of any
+// process function and reported as an error of the item wrapping ErrItemPanic. This includes
+// panics of steps run concurrently by combinators such as InParallel.
+func (c *ParallelQueue[ITEM]) OnProcessPipeline(pipeline PipelineStep) *ParallelQueue[ITEM] {
+	c.batchFunc = nil
+	c.processFunc = func(item ITEM) error {
+		// The parallel combinators hand the panics of their steps on to the worker.
+		exec := &execution{ctx: context.Background(), forwardPanics: true}
+
+		_, err := exec.call(pipeline, item, nil)
+		return err
+	}
 	return c
 }
 
@@ -49,6 +188,15 @@ func (c *ParallelQueue[ITEM]) WithProgressNotifier(batch int, progressFunc Progr
 	return c
 }
 
+// WithTimedProgressNotifier sets a progress notification function that is called once per
+// interval of wall-clock time, which gives steady updates no matter how long the items take,
+// and a final time once the processing completed. It can be combined with WithProgressNotifier.
+func (c *ParallelQueue[ITEM]) WithTimedProgressNotifier(interval time.Duration, progressFunc ProgressNotifier) *ParallelQueue[ITEM] {
+	c.timedProgressInterval = interval
+	c.timedProgressFunc = progressFunc
+	return c
+}
+
 // WithErrorNotifier sets the error notification function.
 // errorFunc is the function to call when an error occurs during processing.
 func (c *ParallelQueue[ITEM]) WithErrorNotifier(errorFunc ErrorNotifier[ITEM]) *ParallelQueue[ITEM] {
@@ -56,62 +204,355 @@ func (c *ParallelQueue[ITEM]) WithErrorNotifier(errorFunc ErrorNotifier[ITEM]) *
 	return c
 }
 
+// WithWorkerAssignmentNotifier sets a function that is called by a worker right before
+// it processes an item. Workers are identified by an id in [0, numberOfWorkers).
+func (c *ParallelQueue[ITEM]) WithWorkerAssignmentNotifier(assignmentFunc func(workerID int, item ITEM)) *ParallelQueue[ITEM] {
+	c.assignmentFunc = assignmentFunc
+	return c
+}
+
+// WithRetries makes the queue process a failing item up to attempts times in total, sleeping
+// for backoff between the attempts. An item is only considered errored, and the error
+// notifier only called, if its last attempt fails as well.
+func (c *ParallelQueue[ITEM]) WithRetries(attempts int, backoff time.Duration) *ParallelQueue[ITEM] {
+	c.retryAttempts = attempts
+	c.retryBackoff = backoff
+	return c
+}
+
+// WithMaxErrors makes the queue fail fast once n items failed to process. No further items
+// are handed to the workers then, while the items already being processed are allowed to
+// finish. Process returns the errored items so far together with an error wrapping ErrMaxErrors.
+func (c *ParallelQueue[ITEM]) WithMaxErrors(n int) *ParallelQueue[ITEM] {
+	c.maxErrors = n
+	return c
+}
+
+// WithRateLimiter makes every worker wait for rl before it processes an item, including
+// each retry of an item, so the rate limiting cannot be forgotten in the process function.
+// Waiting is aborted once the queue is cancelled or stopped; an item that was not processed
+// at all by then is neither counted as processed nor as errored.
+func (c *ParallelQueue[ITEM]) WithRateLimiter(rl *RateLimiter) *ParallelQueue[ITEM] {
+	c.rateLimiter = rl
+	return c
+}
+
+// WithMaxInFlight bounds the number of concurrent operations dispatched by the function set
+// with OnProcessItemInFlight to n, independently of the number of workers. Process waits
+// for all operations to be released before it returns. Without it, the InFlight handed to
+// the function does not limit the operations.
+func (c *ParallelQueue[ITEM]) WithMaxInFlight(n int) *ParallelQueue[ITEM] {
+	c.inFlight = NewInFlight(n)
+	return c
+}
+
+// WithMaxErroredRetained caps the number of errored items returned by Process to the first n,
+// so that a huge number of failures cannot exhaust the memory. Further errored items are
+// still passed to the error notifier and counted in the error of Process and in Stats, but
+// not retained.
+func (c *ParallelQueue[ITEM]) WithMaxErroredRetained(n int) *ParallelQueue[ITEM] {
+	c.maxErroredRetained = n
+	return c
+}
+
+// WithContext sets a context to stop the processing early. Once ctx is cancelled, no
+// further items are handed to the workers, while the items already being processed are
+// allowed to finish. Process then returns the errored items so far together with ctx.Err().
+func (c *ParallelQueue[ITEM]) WithContext(ctx context.Context) *ParallelQueue[ITEM] {
+	c.ctx = ctx
+	return c
+}
+
+// Stop stops the processing from another goroutine. No further items are handed to the
+// workers, while the items already being processed are allowed to finish. Process then
+// returns the errored items so far without reporting the stop as an error. A queue that
+// is stopped before Process is called processes no items at all. A stop only applies to a
+// single run, so the queue can be processed again afterwards. It is safe to call Stop
+// multiple times.
+func (c *ParallelQueue[ITEM]) Stop() {
+	c.stopMutex.Lock()
+	defer c.stopMutex.Unlock()
+
+	c.stopped = true
+	if c.stopFunc != nil {
+		c.stopFunc()
+	}
+}
+
+// WithShardSelector restricts the queue to the items of a single shard, so that a huge item
+// list can be split across shardCount processes without pre-splitting it. Only items for
+// which keyFn(item) % shardCount == shardIndex are processed; all other items are skipped
+// and neither count as processed nor as errored.
+func (c *ParallelQueue[ITEM]) WithShardSelector(shardIndex, shardCount int, keyFn func(ITEM) uint64) *ParallelQueue[ITEM] {
+	c.shardIndex = shardIndex
+	c.shardCount = shardCount
+	c.shardKeyFunc = keyFn
+	return c
+}
+
+// ProcessStats summarizes a single run of Process.
+type ProcessStats struct {
+	// Duration is the wall-clock time from the start to the end of the processing.
+	Duration time.Duration
+	// Items is the number of items processed, including the errored ones.
+	Items int
+	// Errors is the number of items that failed to process.
+	Errors int
+	// ItemsPerSecond is the number of items processed per second of Duration.
+	ItemsPerSecond float64
+	// Unforwarded is the number of successfully processed items that were not forwarded to
+	// the channel set with WithOutputChannel, because the processing was stopped early.
+	Unforwarded int
+}
+
+// Stats returns the statistics of the last call to Process. If Process returned before
+// processing started, e.g. because of an invalid configuration, the statistics are empty.
+func (c *ParallelQueue[ITEM]) Stats() ProcessStats {
+	return c.stats
+}
+
+// ItemError pairs an item that failed to process with the error it failed with.
+type ItemError[ITEM any] struct {
+	Item ITEM
+	Err  error
+}
+
+// ItemErrorMap converts itemErrors into a map from each failed item to its error. It requires
+// comparable items; for other items the slice returned by ProcessWithErrors is used as is.
+// If an item failed more than once, the map holds the last of its errors.
+func ItemErrorMap[ITEM comparable](itemErrors []ItemError[ITEM]) map[ITEM]error {
+	errs := make(map[ITEM]error, len(itemErrors))
+	for _, itemErr := range itemErrors {
+		errs[itemErr.Item] = itemErr.Err
+	}
+	return errs
+}
+
 // Process starts the parallel processing of the enqueued items. It returns a slice of items
 // that failed to process and an error if any critical error occurred during setup or processing.
 func (c *ParallelQueue[ITEM]) Process() (*[]ITEM, error) {
+	itemErrors, err := c.ProcessWithErrors()
+
 	var erroredItems []ITEM
+	for _, itemErr := range *itemErrors {
+		erroredItems = append(erroredItems, itemErr.Item)
+	}
+
+	return &erroredItems, err
+}
+
+// ProcessWithin works like Process, but stops the processing once d has passed and returns
+// the items that were not processed by then, in their original order, alongside the items that
+// failed to process. Items in flight when the time is up are still completed. Running out of
+// time is not an error, so the error is nil unless items failed to process or the context set
+// with WithContext was cancelled. With WithItemChannel, only the items already taken from the
+// channel are returned as unprocessed; the others remain in the channel. With WithOutputChannel,
+// the processed items that could not be forwarded in time are returned as unprocessed as well.
+func (c *ParallelQueue[ITEM]) ProcessWithin(d time.Duration) (*[]ITEM, *[]ITEM, error) {
+	configuredCtx, parentCtx := c.ctx, c.ctx
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+
+	ctx, cancel := context.WithTimeout(parentCtx, d)
+	defer cancel()
+
+	c.ctx, c.unprocessed = ctx, &unprocessedItems[ITEM]{}
+	defer func() {
+		c.ctx, c.unprocessed = configuredCtx, nil
+	}()
+
+	erroredItems, err := c.Process()
+
+	// Running out of time is the expected outcome, so only the errors of the
+	// processed items are reported, as Process does for a complete run.
+	if errors.Is(err, context.DeadlineExceeded) && parentCtx.Err() == nil {
+		err = nil
+		if c.stats.Errors > 0 {
+			err = fmt.Errorf("encountered %d errors during processing", c.stats.Errors)
+		}
+	}
+
+	slices.SortFunc(c.unprocessed.items, func(a, b indexedItem[ITEM]) int {
+		return cmp.Compare(a.index, b.index)
+	})
+
+	unprocessedItems := make([]ITEM, 0, len(c.unprocessed.items))
+	for _, next := range c.unprocessed.items {
+		unprocessedItems = append(unprocessedItems, next.item)
+	}
+
+	return erroredItems, &unprocessedItems, err
+}
+
+// ProcessWithErrors works like Process, but returns every item that failed to process
+// together with the error it failed with, in no particular order.
+func (c *ParallelQueue[ITEM]) ProcessWithErrors() (*[]ItemError[ITEM], error) {
+	var erroredItems []ItemError[ITEM]
+	c.stats = ProcessStats{}
+
+	if c.outputCh != nil {
+		defer close(c.outputCh)
+	}
+
+	if c.autoWorkers {
+		itemCount := 0
+		if c.items != nil {
+			itemCount = len(*c.items)
+		}
+		c.numberOfWorkers = AutoWorkers(itemCount)
+	}
 
 	if c.numberOfWorkers <= 0 {
-		return &erroredItems, fmt.Errorf("number of workers must be positive")
+		return &erroredItems, ErrNoWorkers
 	}
 
-	if c.items == nil || len(*c.items) == 0 {
-		return &erroredItems, fmt.Errorf("items must be non-nil and non-empty")
+	if c.itemCh == nil && (c.items == nil || len(*c.items) == 0) {
+		return &erroredItems, ErrNoItems
 	}
 
-	if c.processFunc == nil {
-		return &erroredItems, fmt.Errorf("process function must be set")
+	if c.processFunc == nil && c.indexedFunc == nil && c.batchFunc == nil {
+		return &erroredItems, ErrNoProcessFunc
 	}
 
-	itemCh := make(chan ITEM, c.numberOfWorkers)
+	batchSize := 1
+	if c.batchFunc != nil {
+		if c.batchSize <= 0 {
+			return &erroredItems, fmt.Errorf("batch size must be positive")
+		}
+		batchSize = c.batchSize
+	}
+
+	if c.shardKeyFunc != nil && (c.shardCount <= 0 || c.shardIndex < 0 || c.shardIndex >= c.shardCount) {
+		return &erroredItems, fmt.Errorf("shard index %d is out of range for %d shards", c.shardIndex, c.shardCount)
+	}
+
+	parentCtx := c.ctx
+	if parentCtx == nil {
+		parentCtx = context.Background()
+	}
+
+	// ctx is additionally cancelled once the maximum number of errors is reached or the
+	// queue is stopped, which stops the processing just like a cancellation of the parent context.
+	ctx, cancel := context.WithCancel(parentCtx)
+	defer cancel()
+
+	c.stopMutex.Lock()
+	c.stopFunc = cancel
+	if c.stopped {
+		cancel()
+	}
+	c.stopMutex.Unlock()
+
+	// The stop is consumed by this run, so that a later run is not stopped right away.
+	defer func() {
+		c.stopMutex.Lock()
+		defer c.stopMutex.Unlock()
+
+		c.stopped, c.stopFunc = false, nil
+	}()
+
+	var errorCount atomic.Int64
+	var unforwarded atomic.Int64
+	var maxErrorsReached atomic.Bool
+
+	// Items are handed to the workers in batches, which hold a single
+	// item each unless a batch function is set.
+	itemCh := make(chan []indexedItem[ITEM], c.numberOfWorkers)
 
 	var wg sync.WaitGroup
 	wg.Add(c.numberOfWorkers)
 
-	// errCh is buffered to avoid blocking workers if the errorFunc is slow or the
-	// error channel is not consumed quickly enough. The size is set to the total
-	// number of items as a safe upper bound.
-	errCh := make(chan ITEM, len(*c.items))
+	// errCh is drained into erroredItems while the workers are running, as the total
+	// number of items is not known up front when they are taken from a channel.
+	errCh := make(chan ItemError[ITEM], c.numberOfWorkers)
+	collected := make(chan struct{})
+
+	// errorsTotal counts all errored items, including those not retained.
+	var errorsTotal int
+
+	go func() {
+		defer close(collected)
+		for itemErr := range errCh {
+			errorsTotal++
+			if c.maxErroredRetained <= 0 || len(erroredItems) < c.maxErroredRetained {
+				erroredItems = append(erroredItems, itemErr)
+			}
+		}
+	}()
 
 	startTime := time.Now()
+	processedBefore := c.processed
+
+	stopTimedProgress := startTimedProgress(c.timedProgressInterval, c.timedProgressFunc, startTime, func() int {
+		c.processedMutex.Lock()
+		defer c.processedMutex.Unlock()
+		return c.processed
+	})
+
+	// firstPanic holds the error of the first batch whose processing panicked,
+	// so that it can be surfaced by Process alongside the partial results.
+	var firstPanic error
+	var firstPanicMutex sync.Mutex
 
 	// worker is the function executed by each goroutine to process items from the item channel.
-	worker := func() {
+	worker := func(workerID int) {
 		defer wg.Done()
-		for item := range itemCh {
-			if err := c.processFunc(item); err != nil {
-				select {
-				// Attempt to send the errored item to the error channel.
-				case errCh <- item:
-					if c.errorFunc != nil {
-						c.errorFunc(err, item)
+		for batch := range itemCh {
+			// Items still buffered in the channel after a cancellation are drained
+			// without being processed, only the ones in flight get to finish.
+			if ctx.Err() != nil {
+				c.unprocessed.add(batch)
+				continue
+			}
+
+			if c.assignmentFunc != nil {
+				for _, next := range batch {
+					c.assignmentFunc(workerID, next.item)
+				}
+			}
+
+			err := c.processWithRetries(ctx, batch)
+			if errors.Is(err, errNotProcessed) {
+				c.unprocessed.add(batch)
+				continue
+			}
+
+			if err != nil {
+				if c.maxErrors > 0 && errorCount.Add(int64(len(batch))) >= int64(c.maxErrors) && maxErrorsReached.CompareAndSwap(false, true) {
+					cancel()
+				}
+
+				if errors.Is(err, ErrItemPanic) {
+					firstPanicMutex.Lock()
+					if firstPanic == nil {
+						firstPanic = err
 					}
-				// If the error channel is full, we report this as an error
-				// before attempting to report the original processing error.
-				default:
+					firstPanicMutex.Unlock()
+				}
+
+				for _, next := range batch {
+					errCh <- ItemError[ITEM]{Item: next.item, Err: err}
 					if c.errorFunc != nil {
-						c.errorFunc(fmt.Errorf("error channel is full"), item)
-						c.errorFunc(err, item)
+						c.errorFunc(err, next.item)
 					}
 				}
+			} else if c.outputCh != nil {
+				if rest := c.forward(ctx, batch); len(rest) > 0 {
+					unforwarded.Add(int64(len(rest)))
+					c.unprocessed.add(rest)
+				}
 			}
 
 			c.processedMutex.Lock()
-			c.processed++
+			previousProcessed := c.processed
+			c.processed += len(batch)
 			currentProcessed := c.processed
 			c.processedMutex.Unlock()
 
-			if c.progressFunc != nil && currentProcessed%c.progressBatch == 0 {
+			// The progress function is called whenever the number of processed items
+			// crosses a multiple of the progress batch, which a batch may skip over.
+			if c.progressFunc != nil && currentProcessed/c.progressBatch != previousProcessed/c.progressBatch {
 				duration := time.Since(startTime)
 				itemsPerSecond := float64(currentProcessed) / duration.Seconds()
 				c.progressFunc(currentProcessed, duration, itemsPerSecond)
@@ -121,29 +562,242 @@ func (c *ParallelQueue[ITEM]) Process() (*[]ITEM, error) {
 
 	// Start the worker goroutines. We use c.numberOfWorkers to determine how many
 	// goroutines to start. Each goroutine will process items from the item channel.
-	for i := 0; i < c.numberOfWorkers; i++ {
-		go worker()
+	for workerID := 0; workerID < c.numberOfWorkers; workerID++ {
+		go worker(workerID)
 	}
 
 	// Goroutine to send items to the item channel. The channel gets
-	// closed when all items have been sent.
+	// closed when all items have been sent or the context is cancelled.
 	go func() {
-		for _, item := range *c.items {
-			itemCh <- item
+		defer close(itemCh)
+
+		batch := make([]indexedItem[ITEM], 0, batchSize)
+		send := func() bool {
+			select {
+			case itemCh <- batch:
+				batch = make([]indexedItem[ITEM], 0, batchSize)
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		for index, item := range c.source(ctx) {
+			if !c.inShard(item) {
+				continue
+			}
+
+			batch = append(batch, indexedItem[ITEM]{index: index, item: item})
+			if len(batch) == batchSize && !send() {
+				c.unprocessed.add(batch)
+				c.unprocessed.addRemaining(c, index+1)
+				return
+			}
+		}
+
+		// The last batch is delivered even if it is not full.
+		if len(batch) > 0 && !send() {
+			c.unprocessed.add(batch)
 		}
-		close(itemCh)
 	}()
 
 	wg.Wait()
+	c.inFlight.Wait()
+	stopTimedProgress()
 	close(errCh)
+	<-collected
+
+	c.stats = ProcessStats{
+		Duration:    time.Since(startTime),
+		Items:       c.processed - processedBefore,
+		Errors:      errorsTotal,
+		Unforwarded: int(unforwarded.Load()),
+	}
+	if seconds := c.stats.Duration.Seconds(); seconds > 0 {
+		c.stats.ItemsPerSecond = float64(c.stats.Items) / seconds
+	}
+
+	if err := parentCtx.Err(); err != nil {
+		return &erroredItems, err
+	}
 
-	for err := range errCh {
-		erroredItems = append(erroredItems, err)
+	if maxErrorsReached.Load() {
+		return &erroredItems, fmt.Errorf("%w: encountered %d errors during processing", ErrMaxErrors, errorsTotal)
 	}
 
-	if len(erroredItems) > 0 {
-		return &erroredItems, fmt.Errorf("encountered %d errors during processing", len(erroredItems))
+	if firstPanic != nil {
+		return &erroredItems, fmt.Errorf("encountered %d errors during processing: %w", errorsTotal, firstPanic)
+	}
+
+	if errorsTotal > 0 {
+		return &erroredItems, fmt.Errorf("encountered %d errors during processing", errorsTotal)
 	}
 
 	return &erroredItems, nil
 }
+
+// processWithRetries processes batch, retrying it as configured with WithRetries. Waiting
+// for the rate limiter or the next attempt is aborted when ctx is cancelled, returning the
+// last error, or errNotProcessed if batch was not processed at all.
+func (c *ParallelQueue[ITEM]) processWithRetries(ctx context.Context, batch []indexedItem[ITEM]) error {
+	var err error
+
+	for attempt := 1; ; attempt++ {
+		if waitErr := c.waitRateLimit(ctx); waitErr != nil {
+			if err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return errNotProcessed
+			}
+			return waitErr
+		}
+
+		err = c.process(batch)
+		if err == nil || attempt >= c.retryAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(c.retryBackoff):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// waitRateLimit waits until the rate limiter, if any, allows the next batch. It returns
+// ctx.Err() if ctx is cancelled first. Unlike rate.Limiter.Wait, it does not give up early
+// if the deadline of ctx is closer than the delay, so a deadline ends the processing just
+// like a cancellation.
+func (c *ParallelQueue[ITEM]) waitRateLimit(ctx context.Context) error {
+	if c.rateLimiter == nil {
+		return nil
+	}
+
+	reservation := c.rateLimiter.limiter.Reserve()
+	if !reservation.OK() {
+		return fmt.Errorf("rate limiter does not allow any items")
+	}
+
+	timer := time.NewTimer(reservation.Delay())
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	}
+}
+
+// process runs the batch function for batch, or the process function for its single item.
+// A panic raised by either function is recovered and returned as an error wrapping
+// ErrItemPanic, so that a single bad item cannot take down the whole queue and every
+// worker runs to completion.
+func (c *ParallelQueue[ITEM]) process(batch []indexedItem[ITEM]) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, _ := recovered(r)
+			err = fmt.Errorf("%w: %v", ErrItemPanic, value)
+		}
+	}()
+
+	if c.batchFunc != nil {
+		items := make([]ITEM, len(batch))
+		for i, next := range batch {
+			items[i] = next.item
+		}
+		return c.batchFunc(items)
+	}
+
+	if c.indexedFunc != nil {
+		return c.indexedFunc(batch[0].index, batch[0].item)
+	}
+	return c.processFunc(batch[0].item)
+}
+
+// indexedItem is an item handed to a worker together with its index in the source.
+type indexedItem[ITEM any] struct {
+	index int
+	item  ITEM
+}
+
+// forward sends the items of batch to the output channel until ctx is cancelled. It returns
+// the items that were not sent.
+func (c *ParallelQueue[ITEM]) forward(ctx context.Context, batch []indexedItem[ITEM]) []indexedItem[ITEM] {
+	for i, next := range batch {
+		select {
+		case c.outputCh <- next.item:
+		case <-ctx.Done():
+			return batch[i:]
+		}
+	}
+	return nil
+}
+
+// unprocessedItems collects the items a cancelled run did not process.
+type unprocessedItems[ITEM any] struct {
+	mu    sync.Mutex
+	items []indexedItem[ITEM]
+}
+
+// add records the items of batch as unprocessed. It is a no-op on nil unprocessedItems.
+func (u *unprocessedItems[ITEM]) add(batch []indexedItem[ITEM]) {
+	if u == nil {
+		return
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.items = append(u.items, batch...)
+}
+
+// addRemaining records the items of the slice of c from index on, which were never taken
+// from it, as unprocessed. Items taken from a channel stay in the channel instead.
+func (u *unprocessedItems[ITEM]) addRemaining(c *ParallelQueue[ITEM], from int) {
+	if u == nil || c.itemCh != nil {
+		return
+	}
+
+	var remaining []indexedItem[ITEM]
+	for index := from; index < len(*c.items); index++ {
+		if item := (*c.items)[index]; c.inShard(item) {
+			remaining = append(remaining, indexedItem[ITEM]{index: index, item: item})
+		}
+	}
+	u.add(remaining)
+}
+
+// source returns the items to be processed by their index, taken either from the slice set
+// with WithItems or the channel set with WithItemChannel. Waiting for items on the channel
+// is aborted once ctx is cancelled.
+func (c *ParallelQueue[ITEM]) source(ctx context.Context) iter.Seq2[int, ITEM] {
+	if c.itemCh == nil {
+		return slices.All(*c.items)
+	}
+
+	return func(yield func(int, ITEM) bool) {
+		for index := 0; ; index++ {
+			select {
+			case item, ok := <-c.itemCh:
+				if !ok || !yield(index, item) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// inShard reports whether item belongs to the shard selected with WithShardSelector.
+// Without a shard selector, every item belongs to the shard.
+func (c *ParallelQueue[ITEM]) inShard(item ITEM) bool {
+	if c.shardKeyFunc == nil {
+		return true
+	}
+	return c.shardKeyFunc(item)%uint64(c.shardCount) == uint64(c.shardIndex)
+}
@@ -1,13 +1,19 @@
 package kyro_test
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/loggdme/kyro"
+	"github.com/loggdme/kyro/kyrotest"
 )
 
 func TestParallelQueue_Done_Success(t *testing.T) {
@@ -182,3 +188,1108 @@ func TestParallelQueue_Done_ProgressNotifier(t *testing.T) {
 		t.Errorf("expected at least %d progress notifications, got %d", len(expectedNotifications), len(progressNotifications))
 	}
 }
+
+func TestParallelQueue_OnProcessPipeline(t *testing.T) {
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i + 1
+	}
+
+	var sum int
+	var mu sync.Mutex
+
+	pipeline := kyro.InSequence(
+		kyro.AsPipelineStep(func(item int, err error) (int, error) {
+			return item * 2, err
+		}),
+		kyro.AsPipelineStep(func(doubled int, err error) (int, error) {
+			if doubled == 20 {
+				return 0, errors.New("rejected item 10")
+			}
+
+			mu.Lock()
+			sum += doubled
+			mu.Unlock()
+			return doubled, err
+		}),
+	)
+
+	erroredItems, err := kyro.NewParallelQueue[int](5).
+		WithItems(&items).
+		OnProcessPipeline(pipeline).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if len(*erroredItems) != 1 || (*erroredItems)[0] != 10 {
+		t.Errorf("expected errored items [10], got %v", *erroredItems)
+	}
+	if expected := 2*(50*51/2) - 20; sum != expected {
+		t.Errorf("expected sum %d, got %d", expected, sum)
+	}
+}
+
+func TestParallelQueue_OnProcessPipeline_RecoversPanic(t *testing.T) {
+	items := []string{"a", "b"}
+	var notified []error
+	var mu sync.Mutex
+
+	erroredItems, err := kyro.NewParallelQueue[string](2).
+		WithItems(&items).
+		OnProcessPipeline(kyro.AsPipelineStep(addOneStep)).
+		WithErrorNotifier(func(err error, item string) {
+			mu.Lock()
+			notified = append(notified, err)
+			mu.Unlock()
+		}).
+		Process()
+
+	if !errors.Is(err, kyro.ErrItemPanic) {
+		t.Errorf("expected error wrapping ErrItemPanic, got: %v", err)
+	}
+	if len(*erroredItems) != 2 {
+		t.Errorf("expected 2 errored items, got %v", *erroredItems)
+	}
+	if len(notified) != 2 {
+		t.Errorf("expected 2 notified errors, got %v", notified)
+	}
+	for _, notifiedErr := range notified {
+		if !errors.Is(notifiedErr, kyro.ErrItemPanic) {
+			t.Errorf("expected error wrapping ErrItemPanic, got: %v", notifiedErr)
+		}
+	}
+}
+
+func TestParallelQueue_OnProcessPipeline_RecoversParallelPanic(t *testing.T) {
+	items := []int{1, 2}
+
+	erroredItems, err := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		OnProcessPipeline(kyro.InParallel(
+			kyro.AsPipelineStep(addOneStep),
+			kyro.AsPipelineStep(func(input int, err error) (int, error) {
+				panic("boom")
+			}),
+		)).
+		Process()
+
+	if !errors.Is(err, kyro.ErrItemPanic) {
+		t.Errorf("expected error wrapping ErrItemPanic, got: %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected the panic value in the error, got: %v", err)
+	}
+	if len(*erroredItems) != 2 {
+		t.Errorf("expected 2 errored items, got %v", *erroredItems)
+	}
+}
+
+func TestParallelQueue_Process_ValidationSentinels(t *testing.T) {
+	items := []int{1, 2}
+	empty := []int{}
+	process := func(item int) error { return nil }
+
+	tests := []struct {
+		name     string
+		queue    *kyro.ParallelQueue[int]
+		expected error
+	}{
+		{
+			name:     "no workers",
+			queue:    kyro.NewParallelQueue[int](0).WithItems(&items).OnProcessItem(process),
+			expected: kyro.ErrNoWorkers,
+		},
+		{
+			name:     "nil items",
+			queue:    kyro.NewParallelQueue[int](2).OnProcessItem(process),
+			expected: kyro.ErrNoItems,
+		},
+		{
+			name:     "empty items",
+			queue:    kyro.NewParallelQueue[int](2).WithItems(&empty).OnProcessItem(process),
+			expected: kyro.ErrNoItems,
+		},
+		{
+			name:     "no process function",
+			queue:    kyro.NewParallelQueue[int](2).WithItems(&items),
+			expected: kyro.ErrNoProcessFunc,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.queue.Process()
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestParallelQueue_WithShardSelector(t *testing.T) {
+	items := make([]int, 30)
+	for i := range items {
+		items[i] = i
+	}
+
+	var processed []int
+	var mu sync.Mutex
+
+	erroredItems, err := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		WithShardSelector(1, 3, func(item int) uint64 { return uint64(item) }).
+		OnProcessItem(func(item int) error {
+			mu.Lock()
+			processed = append(processed, item)
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(*erroredItems) != 0 {
+		t.Errorf("expected no errored items, got %v", *erroredItems)
+	}
+
+	slices.Sort(processed)
+	expected := []int{1, 4, 7, 10, 13, 16, 19, 22, 25, 28}
+	if !slices.Equal(processed, expected) {
+		t.Errorf("expected shard items %v, got %v", expected, processed)
+	}
+}
+
+func TestParallelQueue_WithShardSelector_InvalidShard(t *testing.T) {
+	items := []int{1}
+
+	_, err := kyro.NewParallelQueue[int](1).
+		WithItems(&items).
+		WithShardSelector(3, 3, func(item int) uint64 { return uint64(item) }).
+		OnProcessItem(func(item int) error { return nil }).
+		Process()
+
+	if err == nil {
+		t.Error("expected error for out of range shard index, got nil")
+	}
+}
+
+func TestParallelQueue_Process_PanicKeepsPartialResults(t *testing.T) {
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i + 1
+	}
+
+	var processed []int
+	var mu sync.Mutex
+
+	erroredItems, err := kyro.NewParallelQueue[int](1).
+		WithItems(&items).
+		OnProcessItem(func(item int) error {
+			if item == 25 {
+				panic("corrupt item")
+			}
+			if item%10 == 0 {
+				return errors.New("regular error")
+			}
+
+			mu.Lock()
+			processed = append(processed, item)
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if !errors.Is(err, kyro.ErrItemPanic) {
+		t.Errorf("expected error wrapping ErrItemPanic, got: %v", err)
+	}
+	if err != nil && !strings.Contains(err.Error(), "encountered 6 errors during processing: panic processing item: corrupt item") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+	if !slices.Contains(*erroredItems, 25) || len(*erroredItems) != 6 {
+		t.Errorf("expected 6 errored items including 25, got %v", *erroredItems)
+	}
+
+	for item := 1; item < 25; item++ {
+		if item%10 != 0 && !slices.Contains(processed, item) {
+			t.Errorf("expected item %d processed before the panic to be kept", item)
+		}
+	}
+	if len(processed) != 44 {
+		t.Errorf("expected 44 processed items, got %d", len(processed))
+	}
+}
+
+func TestParallelQueue_WithContext_CancelStopsEarly(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var processed atomic.Int32
+	var inFlightFinished atomic.Int32
+	var err error
+
+	kyrotest.AssertNoGoroutineLeak(t, func() {
+		_, err = kyro.NewParallelQueue[int](4).
+			WithItems(&items).
+			WithContext(ctx).
+			OnProcessItem(func(item int) error {
+				if processed.Add(1) == 50 {
+					cancel()
+				}
+				time.Sleep(2 * time.Millisecond)
+				inFlightFinished.Add(1)
+				return nil
+			}).
+			Process()
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+	if n := processed.Load(); n >= int32(len(items)) {
+		t.Errorf("expected fewer than %d processed items, got %d", len(items), n)
+	}
+	if processed.Load() != inFlightFinished.Load() {
+		t.Errorf("expected in-flight items to finish, started %d finished %d", processed.Load(), inFlightFinished.Load())
+	}
+}
+
+func TestParallelQueue_WithRetries_TransientFailures(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	var attemptsMutex sync.Mutex
+	attempts := make(map[int]int)
+	var notified atomic.Int32
+
+	erroredItems, err := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		WithRetries(3, time.Millisecond).
+		WithErrorNotifier(func(err error, item int) {
+			notified.Add(1)
+		}).
+		OnProcessItem(func(item int) error {
+			attemptsMutex.Lock()
+			defer attemptsMutex.Unlock()
+
+			attempts[item]++
+			if attempts[item] == 1 {
+				return errors.New("transient error")
+			}
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(*erroredItems) != 0 {
+		t.Errorf("expected no errored items, got %v", *erroredItems)
+	}
+	if n := notified.Load(); n != 0 {
+		t.Errorf("expected error notifier not to be called, got %d calls", n)
+	}
+	for _, item := range items {
+		if attempts[item] != 2 {
+			t.Errorf("expected item %d to be attempted twice, got %d", item, attempts[item])
+		}
+	}
+}
+
+func TestParallelQueue_WithRetries_NotifiesFinalFailureOnly(t *testing.T) {
+	items := []int{1, 2, 3}
+
+	var calls atomic.Int32
+	var notified atomic.Int32
+
+	erroredItems, err := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		WithRetries(3, time.Millisecond).
+		WithErrorNotifier(func(err error, item int) {
+			notified.Add(1)
+		}).
+		OnProcessItem(func(item int) error {
+			calls.Add(1)
+			return errors.New("permanent error")
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if len(*erroredItems) != len(items) {
+		t.Errorf("expected %d errored items, got %v", len(items), *erroredItems)
+	}
+	if n := calls.Load(); n != 9 {
+		t.Errorf("expected 9 attempts, got %d", n)
+	}
+	if n := notified.Load(); n != int32(len(items)) {
+		t.Errorf("expected %d notifications, got %d", len(items), n)
+	}
+}
+
+func TestParallelQueue_WithMaxErrors_StopsNearThreshold(t *testing.T) {
+	items := make([]int, 10000)
+	for i := range items {
+		items[i] = i
+	}
+
+	var processed atomic.Int32
+
+	erroredItems, err := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		WithMaxErrors(10).
+		OnProcessItem(func(item int) error {
+			processed.Add(1)
+			time.Sleep(time.Millisecond)
+			return errors.New("always fails")
+		}).
+		Process()
+
+	if !errors.Is(err, kyro.ErrMaxErrors) {
+		t.Errorf("expected ErrMaxErrors, got: %v", err)
+	}
+	if n := len(*erroredItems); n < 10 || n > 20 {
+		t.Errorf("expected between 10 and 20 errored items, got %d", n)
+	}
+	if n := processed.Load(); int(n) != len(*erroredItems) {
+		t.Errorf("expected every processed item to be errored, processed %d errored %d", n, len(*erroredItems))
+	}
+}
+
+func TestParallelQueue_ProcessWithErrors(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6}
+
+	itemErrors, err := kyro.NewParallelQueue[int](3).
+		WithItems(&items).
+		OnProcessItem(func(item int) error {
+			if item%2 == 0 {
+				return fmt.Errorf("item %d is even", item)
+			}
+			return nil
+		}).
+		ProcessWithErrors()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if len(*itemErrors) != 3 {
+		t.Fatalf("expected 3 item errors, got %d", len(*itemErrors))
+	}
+
+	errs := kyro.ItemErrorMap(*itemErrors)
+	for _, item := range []int{2, 4, 6} {
+		if expected := fmt.Sprintf("item %d is even", item); errs[item] == nil || errs[item].Error() != expected {
+			t.Errorf("expected error %q for item %d, got %v", expected, item, errs[item])
+		}
+	}
+}
+
+func TestParallelQueue_ProcessWithErrors_NonComparableItems(t *testing.T) {
+	items := [][]string{{"ok"}, {"bad", "input"}}
+
+	itemErrors, _ := kyro.NewParallelQueue[[]string](2).
+		WithItems(&items).
+		OnProcessItem(func(item []string) error {
+			if item[0] == "bad" {
+				return errors.New(strings.Join(item, " "))
+			}
+			return nil
+		}).
+		ProcessWithErrors()
+
+	if len(*itemErrors) != 1 {
+		t.Fatalf("expected 1 item error, got %d", len(*itemErrors))
+	}
+	if itemErr := (*itemErrors)[0]; !slices.Equal(itemErr.Item, []string{"bad", "input"}) || itemErr.Err.Error() != "bad input" {
+		t.Errorf("unexpected item error: %+v", itemErr)
+	}
+}
+
+func TestParallelQueue_WithItemChannel(t *testing.T) {
+	items := make(chan int)
+	go func() {
+		defer close(items)
+		for i := range 50 {
+			items <- i
+			if i%10 == 0 {
+				time.Sleep(5 * time.Millisecond)
+			}
+		}
+	}()
+
+	var processed atomic.Int32
+	var progressCalls atomic.Int32
+
+	erroredItems, err := kyro.NewParallelQueue[int](4).
+		WithItemChannel(items).
+		WithProgressNotifier(10, func(curr int, duration time.Duration, itemsPerSecond float64) {
+			progressCalls.Add(1)
+		}).
+		OnProcessItem(func(item int) error {
+			processed.Add(1)
+			if item == 7 {
+				return errors.New("unlucky item")
+			}
+			return nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if !slices.Equal(*erroredItems, []int{7}) {
+		t.Errorf("expected errored items [7], got %v", *erroredItems)
+	}
+	if n := processed.Load(); n != 50 {
+		t.Errorf("expected 50 processed items, got %d", n)
+	}
+	if n := progressCalls.Load(); n != 5 {
+		t.Errorf("expected 5 progress notifications, got %d", n)
+	}
+}
+
+func TestParallelQueue_Stop(t *testing.T) {
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+
+	var processed atomic.Int32
+
+	queue := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		OnProcessItem(func(item int) error {
+			processed.Add(1)
+			time.Sleep(time.Millisecond)
+			return nil
+		})
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		queue.Stop()
+		queue.Stop()
+	}()
+
+	erroredItems, err := queue.Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(*erroredItems) != 0 {
+		t.Errorf("expected no errored items, got %d", len(*erroredItems))
+	}
+	if n := processed.Load(); n == 0 || n >= int32(len(items)) {
+		t.Errorf("expected processing to stop early, processed %d of %d", n, len(items))
+	}
+}
+
+func TestParallelQueue_Stop_OnlyAffectsOneRun(t *testing.T) {
+	items := []int{1, 2, 3}
+
+	var processed atomic.Int32
+	queue := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		OnProcessItem(func(item int) error {
+			processed.Add(1)
+			return nil
+		})
+
+	queue.Stop()
+	if _, err := queue.Process(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := processed.Load(); n != 0 {
+		t.Errorf("expected the stopped run to process no items, processed %d", n)
+	}
+
+	if _, err := queue.Process(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := processed.Load(); n != int32(len(items)) {
+		t.Errorf("expected the next run to process all %d items, processed %d", len(items), n)
+	}
+}
+
+func TestAutoWorkers(t *testing.T) {
+	cpus := runtime.NumCPU()
+
+	for _, itemCount := range []int{-1, 0, 1, 3, cpus, cpus * 10} {
+		workers := kyro.AutoWorkers(itemCount)
+
+		if workers < 1 || workers > cpus {
+			t.Errorf("expected between 1 and %d workers for %d items, got %d", cpus, itemCount, workers)
+		}
+		if itemCount > 0 && workers > itemCount {
+			t.Errorf("expected at most %d workers for %d items, got %d", itemCount, itemCount, workers)
+		}
+	}
+}
+
+func TestParallelQueue_WithAutoWorkers(t *testing.T) {
+	items := []int{1, 2}
+
+	var mu sync.Mutex
+	var processed []int
+
+	erroredItems, err := kyro.NewParallelQueue[int](0).
+		WithItems(&items).
+		WithAutoWorkers().
+		OnProcessItem(func(item int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			processed = append(processed, item)
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(*erroredItems) != 0 {
+		t.Errorf("expected no errored items, got %v", *erroredItems)
+	}
+	if slices.Sort(processed); !slices.Equal(processed, items) {
+		t.Errorf("expected %v to be processed, got %v", items, processed)
+	}
+}
+
+func TestParallelQueue_WithRateLimiter(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	start := time.Now()
+	erroredItems, err := kyro.NewParallelQueue[int](16).
+		WithItems(&items).
+		WithRateLimiter(kyro.NewRateLimiter(100, 1)).
+		OnProcessItem(func(item int) error {
+			return nil
+		}).
+		Process()
+	elapsed := time.Since(start)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(*erroredItems) != 0 {
+		t.Errorf("expected no errored items, got %v", *erroredItems)
+	}
+
+	// With a burst of 1 at 100 per second the last of the 20 items is allowed after 190ms,
+	// no matter how many workers are waiting for the limiter.
+	if elapsed < 180*time.Millisecond {
+		t.Errorf("expected throughput to be bounded to 100 items per second, took %v", elapsed)
+	}
+}
+
+func TestParallelQueue_WithRateLimiter_Cancel(t *testing.T) {
+	items := make([]int, 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var processed atomic.Int32
+
+	erroredItems, err := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		WithContext(ctx).
+		WithRateLimiter(kyro.NewRateLimiter(10, 1)).
+		OnProcessItem(func(item int) error {
+			processed.Add(1)
+			return nil
+		}).
+		Process()
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got: %v", err)
+	}
+	if len(*erroredItems) != 0 {
+		t.Errorf("expected items waiting for the limiter not to be errored, got %d", len(*erroredItems))
+	}
+	if n := processed.Load(); n > 2 {
+		t.Errorf("expected at most 2 processed items, got %d", n)
+	}
+}
+
+func TestParallelQueue_WithWorkerAssignmentNotifier(t *testing.T) {
+	items := make([]int, 200)
+	for i := range items {
+		items[i] = i
+	}
+
+	const workers = 4
+
+	var mu sync.Mutex
+	seen := make(map[int]int)
+
+	_, err := kyro.NewParallelQueue[int](workers).
+		WithItems(&items).
+		WithWorkerAssignmentNotifier(func(workerID int, item int) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[workerID]++
+		}).
+		OnProcessItem(func(item int) error {
+			time.Sleep(100 * time.Microsecond)
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	total := 0
+	for workerID, count := range seen {
+		if workerID < 0 || workerID >= workers {
+			t.Errorf("unexpected worker id %d", workerID)
+		}
+		total += count
+	}
+	if len(seen) != workers {
+		t.Errorf("expected all %d worker ids to be observed, got %v", workers, seen)
+	}
+	if total != len(items) {
+		t.Errorf("expected %d assignments, got %d", len(items), total)
+	}
+}
+
+func TestParallelQueue_OnProcessBatch(t *testing.T) {
+	items := make([]int, 103)
+	for i := range items {
+		items[i] = i
+	}
+
+	const batchSize = 10
+
+	var mu sync.Mutex
+	seen := make(map[int]int)
+	var batches, partialBatches int
+
+	erroredItems, err := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		OnProcessBatch(batchSize, func(batch []int) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			if len(batch) > batchSize {
+				t.Errorf("expected batches of at most %d items, got %d", batchSize, len(batch))
+			}
+			if len(batch) < batchSize {
+				partialBatches++
+			}
+			batches++
+
+			for _, item := range batch {
+				seen[item]++
+			}
+
+			if slices.Contains(batch, 42) {
+				return errors.New("bulk insert failed")
+			}
+			return nil
+		}).
+		Process()
+
+	if err == nil {
+		t.Error("expected error, got nil")
+	}
+	if batches != 11 || partialBatches != 1 {
+		t.Errorf("expected 11 batches with 1 partial batch, got %d with %d partial", batches, partialBatches)
+	}
+	for _, item := range items {
+		if seen[item] != 1 {
+			t.Errorf("expected item %d to be processed exactly once, got %d", item, seen[item])
+		}
+	}
+
+	slices.Sort(*erroredItems)
+	if expected := []int{40, 41, 42, 43, 44, 45, 46, 47, 48, 49}; !slices.Equal(*erroredItems, expected) {
+		t.Errorf("expected the failed batch %v to be errored, got %v", expected, *erroredItems)
+	}
+}
+
+func TestParallelQueue_PanicNotifiesAndContinues(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+
+	var processed atomic.Int32
+	var mu sync.Mutex
+	notified := make(map[int]error)
+
+	erroredItems, err := kyro.NewParallelQueue[int](3).
+		WithItems(&items).
+		WithErrorNotifier(func(err error, item int) {
+			mu.Lock()
+			defer mu.Unlock()
+			notified[item] = err
+		}).
+		OnProcessItem(func(item int) error {
+			if item == 4 {
+				var m map[string]int
+				m["boom"] = 1
+			}
+			processed.Add(1)
+			return nil
+		}).
+		Process()
+
+	if !errors.Is(err, kyro.ErrItemPanic) {
+		t.Errorf("expected error wrapping ErrItemPanic, got: %v", err)
+	}
+	if !slices.Equal(*erroredItems, []int{4}) {
+		t.Errorf("expected errored items [4], got %v", *erroredItems)
+	}
+	if n := processed.Load(); n != 7 {
+		t.Errorf("expected the other 7 items to be processed, got %d", n)
+	}
+	if len(notified) != 1 || notified[4] == nil || !strings.HasPrefix(notified[4].Error(), "panic processing item: assignment to entry in nil map") {
+		t.Errorf("expected a panic error to be notified for item 4, got %v", notified)
+	}
+}
+
+func TestParallelQueue_OnProcessBatch_RecoversPanic(t *testing.T) {
+	items := []int{1, 2, 3, 4}
+
+	erroredItems, err := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		OnProcessBatch(2, func(batch []int) error {
+			if batch[0] == 3 {
+				panic("bad batch")
+			}
+			return nil
+		}).
+		Process()
+
+	if !errors.Is(err, kyro.ErrItemPanic) {
+		t.Errorf("expected error wrapping ErrItemPanic, got: %v", err)
+	}
+	if slices.Sort(*erroredItems); !slices.Equal(*erroredItems, []int{3, 4}) {
+		t.Errorf("expected errored items [3 4], got %v", *erroredItems)
+	}
+}
+
+func TestParallelQueue_Stats(t *testing.T) {
+	items := make([]int, 50)
+	for i := range items {
+		items[i] = i
+	}
+
+	queue := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		OnProcessItem(func(item int) error {
+			time.Sleep(time.Millisecond)
+			if item%10 == 0 {
+				return errors.New("failed")
+			}
+			return nil
+		})
+
+	if stats := queue.Stats(); stats != (kyro.ProcessStats{}) {
+		t.Errorf("expected empty stats before processing, got %+v", stats)
+	}
+
+	_, _ = queue.Process()
+	stats := queue.Stats()
+
+	if stats.Items != len(items) {
+		t.Errorf("expected %d items, got %d", len(items), stats.Items)
+	}
+	if stats.Errors != 5 {
+		t.Errorf("expected 5 errors, got %d", stats.Errors)
+	}
+	if stats.Duration <= 0 || stats.ItemsPerSecond <= 0 {
+		t.Errorf("expected positive duration and throughput, got %+v", stats)
+	}
+	if expected := float64(stats.Items) / stats.Duration.Seconds(); stats.ItemsPerSecond != expected {
+		t.Errorf("expected %f items per second, got %f", expected, stats.ItemsPerSecond)
+	}
+}
+
+func TestParallelQueue_WithMaxInFlight(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	const maxInFlight = 3
+
+	var inFlight, peak, completed atomic.Int32
+
+	_, err := kyro.NewParallelQueue[int](8).
+		WithItems(&items).
+		WithMaxInFlight(maxInFlight).
+		OnProcessItemInFlight(func(item int, limiter *kyro.InFlight) error {
+			release := limiter.Acquire()
+
+			go func() {
+				defer release()
+
+				current := inFlight.Add(1)
+				for {
+					observed := peak.Load()
+					if current <= observed || peak.CompareAndSwap(observed, current) {
+						break
+					}
+				}
+
+				time.Sleep(time.Millisecond)
+				inFlight.Add(-1)
+				completed.Add(1)
+			}()
+
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if n := peak.Load(); n > maxInFlight {
+		t.Errorf("expected at most %d operations in flight, got %d", maxInFlight, n)
+	}
+	if n := completed.Load(); n != int32(len(items)) {
+		t.Errorf("expected all %d operations to complete before Process returned, got %d", len(items), n)
+	}
+}
+
+func TestParallelQueue_WithTimedProgressNotifier(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	const interval = 20 * time.Millisecond
+
+	var mu sync.Mutex
+	var notifiedAt []time.Time
+	var lastCurr int
+
+	_, err := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		WithTimedProgressNotifier(interval, func(curr int, duration time.Duration, itemsPerSecond float64) {
+			mu.Lock()
+			defer mu.Unlock()
+			notifiedAt = append(notifiedAt, time.Now())
+			lastCurr = curr
+		}).
+		OnProcessItem(func(item int) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(notifiedAt) < 3 {
+		t.Fatalf("expected several notifications over ~100ms, got %d", len(notifiedAt))
+	}
+	if lastCurr != len(items) {
+		t.Errorf("expected the final notification to report %d items, got %d", len(items), lastCurr)
+	}
+
+	// The last notification is the final one, which is not bound to the interval.
+	for i := 1; i < len(notifiedAt)-1; i++ {
+		if gap := notifiedAt[i].Sub(notifiedAt[i-1]); gap < interval/2 {
+			t.Errorf("expected notifications to be spaced by about %v, got %v", interval, gap)
+		}
+	}
+}
+
+func TestParallelQueue_WithMaxErroredRetained(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	var notified atomic.Int32
+
+	queue := kyro.NewParallelQueue[int](4).
+		WithItems(&items).
+		WithMaxErroredRetained(10).
+		WithErrorNotifier(func(err error, item int) {
+			notified.Add(1)
+		}).
+		OnProcessItem(func(item int) error {
+			if item%2 == 0 {
+				return errors.New("even item")
+			}
+			return nil
+		})
+
+	erroredItems, err := queue.Process()
+
+	if len(*erroredItems) != 10 {
+		t.Errorf("expected 10 retained errored items, got %d", len(*erroredItems))
+	}
+	if err == nil || err.Error() != "encountered 50 errors during processing" {
+		t.Errorf("expected the total of 50 errors to be reported, got: %v", err)
+	}
+	if errs := queue.Stats().Errors; errs != 50 {
+		t.Errorf("expected 50 errors in the stats, got %d", errs)
+	}
+	if n := notified.Load(); n != 50 {
+		t.Errorf("expected 50 error notifications, got %d", n)
+	}
+}
+
+func TestParallelQueue_ProcessWithin(t *testing.T) {
+	items := make([]int, 100)
+	for i := range items {
+		items[i] = i
+	}
+
+	var mu sync.Mutex
+	processed := make(map[int]bool)
+
+	erroredItems, unprocessedItems, err := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		OnProcessItem(func(item int) error {
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			processed[item] = true
+			mu.Unlock()
+			if item == 3 {
+				return errors.New("failed")
+			}
+			return nil
+		}).
+		ProcessWithin(100 * time.Millisecond)
+
+	if err == nil || errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the failed item to be reported without a deadline error, got: %v", err)
+	}
+	if !slices.Equal(*erroredItems, []int{3}) {
+		t.Errorf("expected errored items [3], got %v", *erroredItems)
+	}
+	if len(processed) == 0 || len(processed) == len(items) {
+		t.Fatalf("expected a part of the items to be processed, got %d", len(processed))
+	}
+
+	// Every item is either processed or returned as unprocessed, in input order.
+	if len(processed)+len(*unprocessedItems) != len(items) {
+		t.Errorf("expected %d processed and unprocessed items, got %d and %d", len(items), len(processed), len(*unprocessedItems))
+	}
+	if !slices.IsSorted(*unprocessedItems) {
+		t.Errorf("expected unprocessed items in input order, got %v", *unprocessedItems)
+	}
+	for _, item := range *unprocessedItems {
+		if processed[item] {
+			t.Errorf("item %d was processed but returned as unprocessed", item)
+		}
+	}
+}
+
+func TestParallelQueue_ProcessWithin_CompletesInTime(t *testing.T) {
+	items := []int{1, 2, 3}
+
+	_, unprocessedItems, err := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		OnProcessItem(func(item int) error { return nil }).
+		ProcessWithin(time.Second)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(*unprocessedItems) != 0 {
+		t.Errorf("expected no unprocessed items, got %v", *unprocessedItems)
+	}
+}
+
+func TestParallelQueue_WithOutputChannel(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	stageOne := make(chan int)
+	go func() {
+		kyro.NewParallelQueue[int](3).
+			WithItems(&items).
+			WithOutputChannel(stageOne).
+			OnProcessItem(func(item int) error {
+				if item%2 == 1 {
+					return errors.New("odd")
+				}
+				return nil
+			}).
+			Process()
+	}()
+
+	var mu sync.Mutex
+	var seen []int
+	_, err := kyro.NewParallelQueue[int](2).
+		WithItemChannel(stageOne).
+		OnProcessItem(func(item int) error {
+			mu.Lock()
+			seen = append(seen, item)
+			mu.Unlock()
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	slices.Sort(seen)
+	if expected := []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}; !slices.Equal(seen, expected) {
+		t.Errorf("expected stage two to see %v, got %v", expected, seen)
+	}
+}
+
+func TestParallelQueue_WithOutputChannel_ReturnsUnforwardedItems(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	// Nobody receives from the output channel, so no item can be forwarded in time.
+	output := make(chan int)
+
+	_, unprocessedItems, err := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		WithOutputChannel(output).
+		OnProcessItem(func(item int) error { return nil }).
+		ProcessWithin(100 * time.Millisecond)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !slices.Equal(*unprocessedItems, items) {
+		t.Errorf("expected the unforwarded items %v to be returned, got %v", items, *unprocessedItems)
+	}
+}
+
+func TestParallelQueue_WithOutputChannel_CountsUnforwardedItems(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	// Nobody receives from the output channel, so no item can be forwarded before the stop.
+	output := make(chan int)
+
+	queue := kyro.NewParallelQueue[int](2).
+		WithItems(&items).
+		WithOutputChannel(output).
+		OnProcessItem(func(item int) error { return nil })
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		queue.Stop()
+	}()
+
+	if _, err := queue.Process(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	stats := queue.Stats()
+	if stats.Unforwarded == 0 || stats.Unforwarded != stats.Items {
+		t.Errorf("expected all %d processed items to be counted as unforwarded, got %d", stats.Items, stats.Unforwarded)
+	}
+}
@@ -0,0 +1,181 @@
+package kyro
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// tailBlockSize is the number of bytes read from the file at once while reading it backwards.
+const tailBlockSize = 64 * 1024
+
+// TailProcessor represents a processor for reading a file backwards and processing its lines
+// in parallel, most recent first. This suits log-style files where the latest records are
+// the most relevant. The lines are handed to the workers in reverse order, starting with the
+// last line of the file; with more than one worker they may complete out of that order.
+type TailProcessor struct {
+	filePath  string
+	lastLines int
+	processor *ParallelFileProcessor
+}
+
+// NewTailProcessor creates a new TailProcessor with the specified number of workers.
+func NewTailProcessor(numberOfWorkers int) *TailProcessor {
+	return &TailProcessor{
+		processor: NewParallelFileProcessor(numberOfWorkers),
+	}
+}
+
+// WithFilePath sets the path to the file to be processed.
+func (t *TailProcessor) WithFilePath(filePath string) *TailProcessor {
+	t.filePath = filePath
+	return t
+}
+
+// WithLastLines limits the processing to the last n lines of the file. Without a limit,
+// or with a limit that is not positive, all lines of the file are processed in reverse.
+func (t *TailProcessor) WithLastLines(n int) *TailProcessor {
+	t.lastLines = n
+	return t
+}
+
+// OnProcessLine sets the function to be used for processing each line.
+func (t *TailProcessor) OnProcessLine(processLineFunc ProcessFunc[[]byte]) *TailProcessor {
+	t.processor.OnProcessLine(processLineFunc)
+	return t
+}
+
+// WithProgressNotifier sets the progress notification function and the batch size.
+// batch is the number of lines processed before the progress function is called.
+func (t *TailProcessor) WithProgressNotifier(batch int, progressFunc ProgressNotifier) *TailProcessor {
+	t.processor.WithProgressNotifier(batch, progressFunc)
+	return t
+}
+
+// WithErrorNotifier sets the error notification function.
+// errorFunc is the function to call when an error occurs during processing.
+func (t *TailProcessor) WithErrorNotifier(errorFunc ErrorNotifier[[]byte]) *TailProcessor {
+	t.processor.WithErrorNotifier(errorFunc)
+	return t
+}
+
+// Process starts the parallel processing of the file from its end. It returns a slice of lines
+// that failed to process and an error if any critical error occurred during setup or processing.
+func (t *TailProcessor) Process() (*[][]byte, error) {
+	if t.filePath == "" {
+		return &[][]byte{}, fmt.Errorf("file path must be set")
+	}
+
+	file, err := os.Open(t.filePath)
+	if err != nil {
+		return &[][]byte{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return &[][]byte{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	t.processor.reader = newReverseLineReader(file, info.Size(), t.lastLines)
+	return t.processor.Process()
+}
+
+// reverseLineReader is an io.Reader yielding the lines of r in reverse order, each terminated
+// by a newline. It reads r backwards in blocks, so only the lines not yet yielded of the
+// current block are kept in memory.
+type reverseLineReader struct {
+	r io.ReaderAt
+
+	// offset is the position in r before which nothing has been read yet.
+	offset int64
+	// buf holds the bytes read from r whose lines have not been yielded yet.
+	buf []byte
+	// pending holds the bytes of the current line not yet returned by Read.
+	pending []byte
+
+	// remaining is the number of lines left to yield, negative for all of them.
+	remaining int
+	// trimmed reports whether the end of r was checked for a terminating newline.
+	trimmed bool
+	done    bool
+}
+
+// newReverseLineReader creates a reverseLineReader for the size bytes of r. It yields at
+// most limit lines, or all lines if limit is not positive.
+func newReverseLineReader(r io.ReaderAt, size int64, limit int) *reverseLineReader {
+	reader := &reverseLineReader{r: r, offset: size, remaining: -1, done: size == 0}
+	if limit > 0 {
+		reader.remaining = limit
+	}
+	return reader
+}
+
+// Read implements io.Reader.
+func (r *reverseLineReader) Read(p []byte) (int, error) {
+	if len(r.pending) == 0 {
+		if r.remaining == 0 {
+			return 0, io.EOF
+		}
+
+		line, err := r.prevLine()
+		if err != nil {
+			return 0, err
+		}
+
+		r.pending = make([]byte, len(line)+1)
+		copy(r.pending, line)
+		r.pending[len(line)] = '\n'
+
+		if r.remaining > 0 {
+			r.remaining--
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// prevLine returns the line preceding the lines yielded so far, or io.EOF once the
+// first line of r has been yielded.
+func (r *reverseLineReader) prevLine() ([]byte, error) {
+	for {
+		if i := bytes.LastIndexByte(r.buf, '\n'); i >= 0 {
+			// The newline terminating the last line of the file
+			// does not start another, empty line after it.
+			if !r.trimmed {
+				r.trimmed = true
+				if i == len(r.buf)-1 {
+					r.buf = r.buf[:i]
+					continue
+				}
+			}
+
+			line := r.buf[i+1:]
+			r.buf = r.buf[:i]
+			return line, nil
+		}
+
+		if r.offset == 0 {
+			if r.done {
+				return nil, io.EOF
+			}
+
+			r.done = true
+			line := r.buf
+			r.buf = nil
+			return line, nil
+		}
+
+		n := min(int64(tailBlockSize), r.offset)
+		r.offset -= n
+
+		block := make([]byte, n, n+int64(len(r.buf)))
+		if _, err := r.r.ReadAt(block, r.offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		r.buf = append(block, r.buf...)
+	}
+}
@@ -0,0 +1,63 @@
+package kyro_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestTailProcessor_LastLineFirst(t *testing.T) {
+	var content strings.Builder
+	for i := range 20000 {
+		fmt.Fprintf(&content, "line %d\n", i)
+	}
+	path := writeTempFile(t, content.String())
+
+	var processed []string
+	erroredLines, err := kyro.NewTailProcessor(1).
+		WithFilePath(path).
+		OnProcessLine(func(line []byte) error {
+			processed = append(processed, string(line))
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*erroredLines) != 0 {
+		t.Errorf("expected no errored lines, got %d", len(*erroredLines))
+	}
+	if len(processed) != 20000 {
+		t.Fatalf("expected 20000 lines, got %d", len(processed))
+	}
+	for i, line := range processed {
+		if expected := fmt.Sprintf("line %d", 19999-i); line != expected {
+			t.Fatalf("expected line %d to be %q, got %q", i, expected, line)
+		}
+	}
+}
+
+func TestTailProcessor_WithLastLines(t *testing.T) {
+	path := writeTempFile(t, "first\n\nthird\nfourth\nfifth")
+
+	var processed []string
+	_, err := kyro.NewTailProcessor(1).
+		WithFilePath(path).
+		WithLastLines(4).
+		OnProcessLine(func(line []byte) error {
+			processed = append(processed, string(line))
+			return nil
+		}).
+		Process()
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"fifth", "fourth", "third", ""}; !slices.Equal(processed, expected) {
+		t.Errorf("expected %q, got %q", expected, processed)
+	}
+}
@@ -0,0 +1,125 @@
+package kyro
+
+import (
+	"context"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StepTrace records a single invocation of a step marked with TraceStep.
+type StepTrace struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// TraceStep marks step for tracing under the given name. When the pipeline runs with
+// ExecuteTraced, every invocation of step is recorded as a StepTrace. Otherwise, the step
+// is invoked as is.
+func TraceStep(name string, step PipelineStep) PipelineStep {
+	return scopedStep(func(exec *execution, input any, lastErr error) (output any, err error) {
+		if exec.locatesPanics() {
+			defer func() {
+				if r := recover(); r != nil {
+					exec.notePanic(name)
+					panic(r)
+				}
+			}()
+		}
+
+		start := time.Now()
+		output, err = exec.call(step, input, lastErr)
+		exec.record(StepTrace{Name: name, Duration: time.Since(start), Err: err})
+
+		return output, err
+	}).pipelineStep()
+}
+
+// ExecuteTraced runs the pipeline like Execute and additionally returns the traces of all
+// steps marked with TraceStep, in the order the steps completed.
+func ExecuteTraced(pipeline PipelineStep) (output any, traces []StepTrace, err error) {
+	exec := &execution{ctx: context.Background(), trace: &tracer{}}
+	output, err = exec.run(pipeline, nil)
+
+	exec.trace.mu.Lock()
+	defer exec.trace.mu.Unlock()
+
+	return output, slices.Clone(exec.trace.traces), err
+}
+
+// StepPercentiles summarizes the durations recorded for a single step.
+type StepPercentiles struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	P99   time.Duration
+}
+
+// StepStats aggregates the durations of named steps across many traced runs, e.g. of the
+// same pipeline executed for every record of a dataset, to find the slowest stage.
+// It is safe for concurrent use by multiple goroutines.
+type StepStats struct {
+	mu        sync.Mutex
+	durations map[string][]time.Duration
+}
+
+// NewStepStats creates a new, empty StepStats.
+func NewStepStats() *StepStats {
+	return &StepStats{durations: make(map[string][]time.Duration)}
+}
+
+// Add records the durations of the given traces, as returned by ExecuteTraced.
+func (s *StepStats) Add(traces []StepTrace) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, trace := range traces {
+		s.durations[trace.Name] = append(s.durations[trace.Name], trace.Duration)
+	}
+}
+
+// Percentile returns the p-th percentile (0 < p <= 100) of the durations recorded for the
+// named step, using the nearest-rank method. It returns 0 if nothing was recorded for it.
+func (s *StepStats) Percentile(name string, p float64) time.Duration {
+	s.mu.Lock()
+	sorted := slices.Clone(s.durations[name])
+	s.mu.Unlock()
+
+	slices.Sort(sorted)
+	return percentile(sorted, p)
+}
+
+// Summary returns the p50, p90 and p99 durations of every recorded step, keyed by step name.
+func (s *StepStats) Summary() map[string]StepPercentiles {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := make(map[string]StepPercentiles, len(s.durations))
+	for name, durations := range s.durations {
+		sorted := slices.Clone(durations)
+		slices.Sort(sorted)
+
+		summary[name] = StepPercentiles{
+			Count: len(sorted),
+			P50:   percentile(sorted, 50),
+			P90:   percentile(sorted, 90),
+			P99:   percentile(sorted, 99),
+		}
+	}
+
+	return summary
+}
+
+// percentile returns the p-th percentile of the sorted durations using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	rank = min(max(rank, 1), len(sorted))
+
+	return sorted[rank-1]
+}
@@ -0,0 +1,111 @@
+package kyro_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestExecuteTraced_RecordsTracedSteps(t *testing.T) {
+	stepErr := errors.New("enrich failed")
+
+	p := kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.TraceStep("parse", kyro.AsPipelineStep(addOneStep)),
+		kyro.AsPipelineStep(multiplyByTwoStep),
+		kyro.TraceStep("enrich", func(input any, err error) (any, error) {
+			time.Sleep(5 * time.Millisecond)
+			return input, stepErr
+		}),
+	)
+
+	output, traces, err := kyro.ExecuteTraced(p)
+
+	if !errors.Is(err, stepErr) {
+		t.Errorf("expected %v, got: %v", stepErr, err)
+	}
+	if output != 22 {
+		t.Errorf("expected output 22, got %v", output)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 traces, got %d", len(traces))
+	}
+	if traces[0].Name != "parse" || traces[0].Err != nil {
+		t.Errorf("unexpected first trace: %+v", traces[0])
+	}
+	if traces[1].Name != "enrich" || !errors.Is(traces[1].Err, stepErr) || traces[1].Duration < 5*time.Millisecond {
+		t.Errorf("unexpected second trace: %+v", traces[1])
+	}
+}
+
+func TestExecuteTraced_RawStepsReceivePlainInput(t *testing.T) {
+	output, traces, err := kyro.ExecuteTraced(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		func(input any, err error) (any, error) {
+			return input.(int) + 1, err
+		},
+		kyro.TraceStep("double", func(input any, err error) (any, error) {
+			return input.(int) * 2, err
+		}),
+	))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 22 {
+		t.Errorf("expected output 22, got %v", output)
+	}
+	if len(traces) != 1 || traces[0].Name != "double" {
+		t.Errorf("expected a single trace for 'double', got %+v", traces)
+	}
+}
+
+func TestTraceStep_WithoutTracing(t *testing.T) {
+	output, err := kyro.Execute(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		kyro.TraceStep("add", kyro.AsPipelineStep(addOneStep)),
+	))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 11 {
+		t.Errorf("expected output 11, got %v", output)
+	}
+}
+
+func TestStepStats_Percentiles(t *testing.T) {
+	stats := kyro.NewStepStats()
+
+	// Feed 1ms..100ms for "fetch" in reverse order and a constant for "parse".
+	for i := 100; i >= 1; i-- {
+		stats.Add([]kyro.StepTrace{
+			{Name: "fetch", Duration: time.Duration(i) * time.Millisecond},
+			{Name: "parse", Duration: time.Millisecond},
+		})
+	}
+
+	summary := stats.Summary()
+
+	fetch := summary["fetch"]
+	if fetch.Count != 100 {
+		t.Errorf("expected 100 fetch durations, got %d", fetch.Count)
+	}
+	if fetch.P50 != 50*time.Millisecond || fetch.P90 != 90*time.Millisecond || fetch.P99 != 99*time.Millisecond {
+		t.Errorf("unexpected fetch percentiles: %+v", fetch)
+	}
+
+	parse := summary["parse"]
+	if parse.P50 != time.Millisecond || parse.P99 != time.Millisecond {
+		t.Errorf("unexpected parse percentiles: %+v", parse)
+	}
+
+	if p := stats.Percentile("fetch", 100); p != 100*time.Millisecond {
+		t.Errorf("expected p100 of 100ms, got %v", p)
+	}
+	if p := stats.Percentile("unknown", 50); p != 0 {
+		t.Errorf("expected 0 for unknown step, got %v", p)
+	}
+}
@@ -26,6 +26,10 @@ type WeightedProportionCheck struct {
 	Condition bool
 }
 
+// CalculateWeightedProportion returns the sum of the scores of all checks whose condition
+// holds, divided by the sum of all scores. Negative scores are not rejected, but as they can
+// push the ratio outside of [0, 1], the result is clamped into that range. If the scores do
+// not add up to a positive total, the result is 0.
 func CalculateWeightedProportion(checks []WeightedProportionCheck) float64 {
 	maxScore, currentScore := 0, 0
 	for _, check := range checks {
@@ -40,7 +44,19 @@ func CalculateWeightedProportion(checks []WeightedProportionCheck) float64 {
 		normalizedScore = float64(currentScore) / float64(maxScore)
 	}
 
-	return normalizedScore
+	return min(max(normalizedScore, 0), 1)
+}
+
+// WeightedProportionEpsilon is the tolerance MeetsThreshold allows below the threshold.
+const WeightedProportionEpsilon = 1e-9
+
+// MeetsThreshold reports whether the weighted proportion of checks, as calculated by
+// CalculateWeightedProportion, is at least threshold. A proportion less than
+// WeightedProportionEpsilon below the threshold still meets it, so that a proportion that
+// equals the threshold mathematically is not rejected for floating-point rounding, as in
+// 3 of 10 against a threshold of 0.1+0.2.
+func MeetsThreshold(checks []WeightedProportionCheck, threshold float64) bool {
+	return CalculateWeightedProportion(checks) >= threshold-WeightedProportionEpsilon
 }
 
 type WeightedSumCheck struct {
@@ -0,0 +1,88 @@
+package kyro_test
+
+import (
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestCalculateWeightedProportion(t *testing.T) {
+	tests := []struct {
+		name     string
+		checks   []kyro.WeightedProportionCheck
+		expected float64
+	}{
+		{
+			name:     "no checks",
+			checks:   nil,
+			expected: 0,
+		},
+		{
+			name: "positive scores",
+			checks: []kyro.WeightedProportionCheck{
+				{Score: 10, Condition: true},
+				{Score: 5, Condition: false},
+				{Score: 15, Condition: true},
+			},
+			expected: 25.0 / 30.0,
+		},
+		{
+			name: "negative score below zero",
+			checks: []kyro.WeightedProportionCheck{
+				{Score: 10, Condition: false},
+				{Score: -3, Condition: true},
+			},
+			expected: 0,
+		},
+		{
+			name: "negative score above one",
+			checks: []kyro.WeightedProportionCheck{
+				{Score: 10, Condition: true},
+				{Score: -3, Condition: false},
+			},
+			expected: 1,
+		},
+		{
+			name: "only negative scores",
+			checks: []kyro.WeightedProportionCheck{
+				{Score: -10, Condition: true},
+			},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kyro.CalculateWeightedProportion(tt.checks); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMeetsThreshold(t *testing.T) {
+	// 3 of 10 evaluates to 0.3, which is just below 0.1+0.2 in floating point.
+	checks := []kyro.WeightedProportionCheck{
+		{Score: 3, Condition: true},
+		{Score: 7, Condition: false},
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		expected  bool
+	}{
+		{name: "at threshold", threshold: 0.3, expected: true},
+		{name: "at threshold with rounding error", threshold: 0.1 + 0.2, expected: true},
+		{name: "just below threshold", threshold: 0.3 + 1e-6, expected: false},
+		{name: "just above threshold", threshold: 0.3 - 1e-6, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := kyro.MeetsThreshold(checks, tt.threshold); got != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
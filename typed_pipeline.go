@@ -0,0 +1,43 @@
+package kyro
+
+// Pipeline is a type-safe sequence of steps taking an input of type I and producing an
+// output of type O. In contrast to PipelineStep, the types of consecutive steps are checked
+// by the compiler, so wiring mistakes surface at build time instead of as a panic in AssertIn.
+// Like InSequence, every step receives the output and error of the previous step.
+type Pipeline[I any, O any] struct {
+	run func(input I, lastErr error) (O, error)
+}
+
+// NewPipeline creates an empty Pipeline that passes its input through unchanged.
+// Steps are appended with Then.
+func NewPipeline[I any]() *Pipeline[I, I] {
+	return &Pipeline[I, I]{
+		run: func(input I, lastErr error) (I, error) {
+			return input, lastErr
+		},
+	}
+}
+
+// Then returns a new Pipeline that runs step on the output of p. As Go methods cannot
+// declare type parameters of their own, Then is a function rather than a method:
+//
+//	p := kyro.Then(kyro.Then(kyro.NewPipeline[int](), intToString), stringLength)
+func Then[I any, M any, O any](p *Pipeline[I, M], step func(input M, lastErr error) (output O, err error)) *Pipeline[I, O] {
+	return &Pipeline[I, O]{
+		run: func(input I, lastErr error) (O, error) {
+			intermediate, err := p.run(input, lastErr)
+			return step(intermediate, err)
+		},
+	}
+}
+
+// Execute runs the pipeline with the given input and returns its typed output.
+func (p *Pipeline[I, O]) Execute(input I) (O, error) {
+	return p.run(input, nil)
+}
+
+// Step converts the pipeline into a PipelineStep, so it can be composed with the untyped
+// combinators such as InSequence and InParallel.
+func (p *Pipeline[I, O]) Step() PipelineStep {
+	return AsPipelineStep(p.run)
+}
@@ -0,0 +1,66 @@
+package kyro_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/loggdme/kyro"
+)
+
+func TestPipeline_Execute(t *testing.T) {
+	stringLength := func(input string, err error) (int, error) {
+		return len(input), err
+	}
+
+	p := kyro.Then(kyro.Then(kyro.NewPipeline[int](), intToStringStep), stringLength)
+
+	output, err := p.Execute(12345)
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 5 {
+		t.Errorf("expected output 5, got %v", output)
+	}
+}
+
+func TestPipeline_ErrorPropagation(t *testing.T) {
+	failing := func(input int, err error) (int, error) {
+		return 0, errors.New("step failed")
+	}
+
+	var receivedErr error
+	last := func(input int, err error) (string, error) {
+		receivedErr = err
+		return "done", err
+	}
+
+	output, err := kyro.Then(kyro.Then(kyro.NewPipeline[int](), failing), last).Execute(1)
+
+	if err == nil || err.Error() != "step failed" {
+		t.Errorf("expected error 'step failed', got: %v", err)
+	}
+	if receivedErr == nil {
+		t.Error("expected the last step to receive the error of the previous step")
+	}
+	if output != "done" {
+		t.Errorf("expected output 'done', got %v", output)
+	}
+}
+
+func TestPipeline_Step(t *testing.T) {
+	typed := kyro.Then(kyro.NewPipeline[int](), addOneStep)
+
+	output, err := kyro.Execute(kyro.InSequence(
+		kyro.AsPipelineGenerator(intGenerator),
+		typed.Step(),
+		kyro.AsPipelineStep(multiplyByTwoStep),
+	))
+
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if output != 22 {
+		t.Errorf("expected output 22, got %v", output)
+	}
+}
@@ -1,14 +1,96 @@
 package kyro
 
 import (
+	"math/rand/v2"
+	"sync"
 	"time"
 )
 
 // ProgressNotifier is a function type for notifying the progress of the queue processing.
 type ProgressNotifier func(curr int, duration time.Duration, itemsPerSecond float64)
 
+// ByteProgressNotifier is a function type for notifying how many bytes of the input have
+// been read. pct is the percentage of totalBytes read, from 0 to 100. totalBytes and pct
+// are 0 if the size of the input is not known.
+type ByteProgressNotifier func(bytesRead, totalBytes int64, pct float64)
+
 // ErrorNotifier is a function type for notifying about errors during processing.
 type ErrorNotifier[ITEM any] func(err error, item ITEM)
 
 // ProcessFunc is a function type for processing an item.
 type ProcessFunc[ITEM any] func(ITEM) error
+
+// startTimedProgress calls progressFunc once per interval from a background goroutine until
+// the returned function is called, which stops the goroutine and calls progressFunc a final
+// time. processed reports the number of processed items so far. Without a progress function
+// nothing is started.
+func startTimedProgress(interval time.Duration, progressFunc ProgressNotifier, startTime time.Time, processed func() int) (stop func()) {
+	if progressFunc == nil {
+		return func() {}
+	}
+
+	notify := func() {
+		curr := processed()
+		duration := time.Since(startTime)
+		progressFunc(curr, duration, float64(curr)/duration.Seconds())
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		if interval <= 0 {
+			<-done
+			return
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				notify()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+		notify()
+	}
+}
+
+var (
+	randMutex sync.Mutex
+	randGen   *rand.Rand
+)
+
+// SetRandSource sets the source of the randomness used by the package, e.g. by
+// StaggeredStart, so that tests and reproductions of production runs can fix it with a
+// seeded source such as rand.NewPCG. A nil src restores the default, randomly seeded source.
+// The source is only used while holding a lock, so it need not be safe for concurrent use.
+func SetRandSource(src rand.Source) {
+	randMutex.Lock()
+	defer randMutex.Unlock()
+
+	randGen = nil
+	if src != nil {
+		randGen = rand.New(src)
+	}
+}
+
+// randInt64N returns a random number in [0, n) from the source set with SetRandSource.
+func randInt64N(n int64) int64 {
+	randMutex.Lock()
+	defer randMutex.Unlock()
+
+	if randGen == nil {
+		return rand.Int64N(n)
+	}
+	return randGen.Int64N(n)
+}